@@ -21,6 +21,7 @@ void setup() {
  *     + 2: Memory stats
  *     + 3: GPU stats
  *     + 4: Network stats
+ *     + 5: Disk stats
  *     + y: Display brightness
  *     + z: Alert
  *   - Depends on command type, there may have one or many values.
@@ -28,6 +29,7 @@ void setup() {
  *   - Command ends with $ character.
  *   - For alert command, first value dertermines the alert type,
  *     second value determines the alert status (0 = OFF, 1 = ON).
+ *     CPU and MEM alerts have the top process as third value when turned on.
  * Examples:
  *  - 1|10|0$
  *  - 2|34|2951$
@@ -71,6 +73,11 @@ void outputToLCD(String input) {
     case '3': { // GPU
       String load = getValue(input, '|', 1);
       String usage = getValue(input, '|', 2);
+      if (load == "N/A") { // GPU watcher is unavailable
+        myNextion.setComponentText("gpu0", load);
+        myNextion.setComponentText("gpu1", usage);
+        break;
+      }
       myNextion.setComponentText("gpu0", load + "%");
       myNextion.setComponentText("gpu1", usage + "MB");
       break;
@@ -81,6 +88,100 @@ void outputToLCD(String input) {
       myNextion.setComponentText("net0", down + "/" + up + "KBps");
       break;
     }
+    case '5': { // DISK
+      String load = getValue(input, '|', 1);
+      String free = getValue(input, '|', 2);
+      myNextion.setComponentText("disk0", load + "%");
+      myNextion.setComponentText("disk1", free + "GB");
+      break;
+    }
+    case '6': { // Top GPU process
+      String name = getValue(input, '|', 1);
+      String usage = getValue(input, '|', 2);
+      myNextion.setComponentText("gpu2", name + " " + usage + "MB");
+      break;
+    }
+    case '7': { // Top processes
+      String cpuName = getValue(input, '|', 1);
+      String cpuLoad = getValue(input, '|', 2);
+      String memName = getValue(input, '|', 3);
+      String memUsage = getValue(input, '|', 4);
+      myNextion.setComponentText("proc0", cpuName + " " + cpuLoad + "%");
+      myNextion.setComponentText("proc1", memName + " " + memUsage + "MB");
+      break;
+    }
+    case '8': { // PSI
+      String cpu = getValue(input, '|', 1);
+      String mem = getValue(input, '|', 2);
+      String io = getValue(input, '|', 3);
+      if (cpu == "N/A") { // PSI is not supported by kernel
+        myNextion.setComponentText("psi0", cpu);
+        break;
+      }
+      myNextion.setComponentText("psi0", cpu + "/" + mem + "/" + io + "%");
+      break;
+    }
+    case '9': { // Kernel event
+      String type = getValue(input, '|', 1);
+      String process = getValue(input, '|', 2);
+      myNextion.setComponentText("kernel0", type + " " + process);
+      break;
+    }
+    case 'c': { // Top cgroup/container
+      String name = getValue(input, '|', 1);
+      String cpu = getValue(input, '|', 2);
+      String mem = getValue(input, '|', 3);
+      myNextion.setComponentText("cgroup0", name + " " + cpu + "%");
+      myNextion.setComponentText("cgroup1", mem + "MB");
+      break;
+    }
+    case 'b': { // Battery
+      String percent = getValue(input, '|', 1);
+      String status = getValue(input, '|', 2);
+      if (percent == "N/A") { // No battery
+        myNextion.setComponentText("battery0", percent);
+        break;
+      }
+      myNextion.setComponentText("battery0", percent + "% " + status);
+      break;
+    }
+    case 'e': { // CPU power and energy cost of today
+      String watts = getValue(input, '|', 1);
+      String energy = getValue(input, '|', 2);
+      String cost = getValue(input, '|', 3);
+      myNextion.setComponentText("power0", watts + "W");
+      myNextion.setComponentText("power1", energy + "kWh " + cost);
+      break;
+    }
+    case 'h': { // Sensor display slot
+      String slot = getValue(input, '|', 1);
+      String label = getValue(input, '|', 2);
+      String value = getValue(input, '|', 3);
+      myNextion.setComponentText("sensor" + slot, label + " " + value);
+      break;
+    }
+    case 'l': { // Log pattern matched the most lines in the last minute
+      String name = getValue(input, '|', 1);
+      String count = getValue(input, '|', 2);
+      String line = getValue(input, '|', 3);
+      myNextion.setComponentText("logs0", name + " " + count + "/min");
+      myNextion.setComponentText("logs1", line);
+      break;
+    }
+    case 'p': { // Probes
+      String up = getValue(input, '|', 1);
+      String down = getValue(input, '|', 2);
+      myNextion.setComponentText("probe0", up + " up");
+      myNextion.setComponentText("probe1", down);
+      break;
+    }
+    case 'u': { // Custom metric display slot
+      String slot = getValue(input, '|', 1);
+      String name = getValue(input, '|', 2);
+      String value = getValue(input, '|', 3);
+      myNextion.setComponentText("custom" + slot, name + " " + value);
+      break;
+    }
     case 'y': { // Display brightness
       String val = getValue(input, '|', 1);
       myNextion.sendCommand(string2char("dim=" + val));
@@ -115,19 +216,51 @@ void applyAlert(String cmd) {
   if (getValue(cmd, '|', 2).charAt(0) == '1') { // Alert status
     alertColor = "57798"; // Red
   }
-  switch (alertType.charAt(0)) {
-    case '1':
+  switch (alertType.toInt()) { // Alert types can have 2 digits
+    case 1:
       myNextion.sendCommand(string2char("page0.cpu_alert.bco=" + alertColor));
+      myNextion.setComponentText("alert0", getValue(cmd, '|', 3)); // Top process or cleared when OFF
       break;
-    case '2':
+    case 2:
       myNextion.sendCommand(string2char("page0.mem_alert.bco=" + alertColor));
+      myNextion.setComponentText("alert1", getValue(cmd, '|', 3));
       break;
-    case '3':
+    case 3:
       myNextion.sendCommand(string2char("page0.gpu_alert.bco=" + alertColor));
       break;
-    case '4':
+    case 4:
       myNextion.sendCommand(string2char("page0.net_alert.bco=" + alertColor));
       break;
+    case 5:
+      myNextion.sendCommand(string2char("page0.disk_alert.bco=" + alertColor));
+      break;
+    case 6:
+      myNextion.sendCommand(string2char("page0.psi_alert.bco=" + alertColor));
+      break;
+    case 7:
+      myNextion.sendCommand(string2char("page0.kernel_alert.bco=" + alertColor));
+      break;
+    case 8:
+      myNextion.sendCommand(string2char("page0.cgroup_alert.bco=" + alertColor));
+      break;
+    case 9:
+      myNextion.sendCommand(string2char("page0.battery_alert.bco=" + alertColor));
+      break;
+    case 10:
+      myNextion.sendCommand(string2char("page0.sensor_alert.bco=" + alertColor));
+      break;
+    case 11:
+      myNextion.sendCommand(string2char("page0.throttle_alert.bco=" + alertColor));
+      break;
+    case 12:
+      myNextion.sendCommand(string2char("page0.custom_alert.bco=" + alertColor));
+      break;
+    case 13:
+      myNextion.sendCommand(string2char("page0.probe_alert.bco=" + alertColor));
+      break;
+    case 14:
+      myNextion.sendCommand(string2char("page0.logs_alert.bco=" + alertColor));
+      break;
     default:
       return;
   }
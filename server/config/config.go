@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"path"
+	"time"
 
 	"github.com/lnquy/nights-watch/server/util"
 	"github.com/sirupsen/logrus"
@@ -41,11 +42,22 @@ type (
 	}
 
 	Stats struct {
-		Interval uint `json:"interval"`
-		CPU           `json:"cpu"`
-		Memory        `json:"memory"`
-		GPU           `json:"gpu"`
-		Network       `json:"network"`
+		Interval Duration `json:"interval"` // Default interval of all watchers
+		CPU      `json:"cpu"`
+		Memory   `json:"memory"`
+		GPU      `json:"gpu"`
+		Network  `json:"network"`
+		Disk     `json:"disk"`
+		Process  `json:"process"`
+		PSI      `json:"psi"`
+		Kernel   `json:"kernel"`
+		Cgroup   `json:"cgroup"`
+		Battery  `json:"battery"`
+		Sensor   `json:"sensor"`
+		Power    `json:"power"`
+		Custom   `json:"custom"`
+		Probe    `json:"probe"`
+		Logs     `json:"logs"`
 	}
 
 	Sleep struct {
@@ -56,27 +68,224 @@ type (
 	}
 
 	CPU struct {
-		Enabled       bool `json:"enabled"`
-		LoadThreshold uint `json:"load"`
-		TempThreshold uint `json:"temp"`
+		Enabled       bool     `json:"enabled"`
+		Interval      Duration `json:"interval,omitempty"`
+		LoadThreshold uint     `json:"load"`
+		TempThreshold uint     `json:"temp"`
+		ThrottleAlert bool     `json:"throttleAlert"` // Alert when CPU is thermal/power limit throttled, Linux only
 	}
 
 	Memory struct {
-		Enabled       bool `json:"enabled"`
-		LoadThreshold uint `json:"load"`
+		Enabled        bool     `json:"enabled"`
+		Interval       Duration `json:"interval,omitempty"`
+		LoadThreshold  uint     `json:"load"`
+		SwapThreshold  uint     `json:"swap"`        // Percent
+		DirtyThreshold uint     `json:"dirty"`       // MB
+		FaultThreshold uint     `json:"majorFaults"` // Major page faults per second
 	}
 
 	GPU struct {
-		Enabled       bool   `json:"enabled"`
-		LoadThreshold uint   `json:"load"`
-		MemThreshold  uint   `json:"mem"`
-		Vendor        string `json:"vendor"`
+		Enabled        bool     `json:"enabled"`
+		Interval       Duration `json:"interval,omitempty"`
+		LoadThreshold  uint     `json:"load"`
+		MemThreshold   uint     `json:"mem"`
+		TempThreshold  uint     `json:"temp"`   // °C
+		PowerThreshold uint     `json:"power"`  // Watts
+		Vendor         string   `json:"vendor"` // nvidia (default), amd or intel
+		// Watch on all cards if empty. Thresholds of each card are checked separately
+		// while the above thresholds are checked on the aggregated stats of all watched cards.
+		Cards       []GPUCard `json:"cards"`
+		Aggregation string    `json:"aggregation"` // max (default), sum or avg
+		Processes   bool      `json:"processes"`   // List processes using the cards
+	}
+
+	GPUCard struct {
+		ID             string `json:"id"` // Card index, UUID or PCI bus ID
+		LoadThreshold  uint   `json:"load"`
+		MemThreshold   uint   `json:"mem"`
+		TempThreshold  uint   `json:"temp"`
+		PowerThreshold uint   `json:"power"`
 	}
 
 	Network struct {
-		Enabled           bool `json:"enabled"`
-		DownloadThreshold uint `json:"download"`
-		UploadThreshold   uint `json:"upload"`
+		Enabled             bool     `json:"enabled"`
+		Interval            Duration `json:"interval,omitempty"`
+		DownloadThreshold   uint     `json:"download"`
+		UploadThreshold     uint     `json:"upload"`
+		ErrorThreshold      uint     `json:"errors"`      // Rx/Tx errors per second
+		DropThreshold       uint     `json:"drops"`       // Rx/Tx drops per second
+		RetransmitThreshold float64  `json:"retransmits"` // Percent of TCP retransmitted segments
+		Include             []string `json:"include"`     // Interface name glob patterns, e.g.: "eth*", "wlan0"
+		Exclude             []string `json:"exclude"`
+	}
+
+	Disk struct {
+		Enabled          bool     `json:"enabled"`
+		Interval         Duration `json:"interval,omitempty"`
+		LoadThreshold    uint     `json:"load"`    // Percent
+		InodesThreshold  uint     `json:"inodes"`  // Percent
+		LatencyThreshold uint     `json:"latency"` // ms
+		Include          []string `json:"include"` // Mount point glob patterns, e.g.: "/", "/mnt/*"
+		Exclude          []string `json:"exclude"`
+	}
+
+	Process struct {
+		Enabled  bool     `json:"enabled"`
+		Interval Duration `json:"interval,omitempty"`
+		Top      int      `json:"top"` // Number of top processes by CPU, memory and I/O
+	}
+
+	// PSI thresholds are checked on "some" stall time percent averaged over 10 seconds,
+	// except MemoryFullThreshold which is checked on "full" memory stall time.
+	PSI struct {
+		Enabled             bool     `json:"enabled"`
+		Interval            Duration `json:"interval,omitempty"`
+		CPUThreshold        uint     `json:"cpu"`
+		MemoryThreshold     uint     `json:"memory"`
+		MemoryFullThreshold uint     `json:"memoryFull"`
+		IOThreshold         uint     `json:"io"`
+	}
+
+	// Kernel events which fire alert, the alert is kept for AlertDuration after the last event.
+	Kernel struct {
+		Enabled       bool     `json:"enabled"`
+		Interval      Duration `json:"interval,omitempty"`
+		OOM           bool     `json:"oom"`
+		Hardware      bool     `json:"hardware"` // MCE and EDAC errors
+		Filesystem    bool     `json:"filesystem"`
+		Segfault      bool     `json:"segfault"`
+		AlertDuration Duration `json:"alertDuration"`
+	}
+
+	Cgroup struct {
+		Enabled      bool              `json:"enabled"`
+		Interval     Duration          `json:"interval,omitempty"`
+		Paths        []string          `json:"paths"` // Relative to cgroup root, e.g.: system.slice/nginx.service
+		Docker       bool              `json:"docker"`
+		DockerSocket string            `json:"dockerSocket"` // Only changeable in config file
+		Thresholds   []CgroupThreshold `json:"thresholds"`
+	}
+
+	// Battery alert is fired when battery level is below LowThreshold while not on AC power,
+	// or battery health is below HealthThreshold.
+	Battery struct {
+		Enabled         bool     `json:"enabled"`
+		Interval        Duration `json:"interval,omitempty"`
+		LowThreshold    uint     `json:"low"`            // Percent
+		HealthThreshold uint     `json:"health"`         // Percent
+		SleepOnBattery  bool     `json:"sleepOnBattery"` // Dim the LCD while not on AC power
+	}
+
+	// Sensor slots are displayed on the LCD in order, each slot is a glob pattern matched against
+	// sensor IDs (e.g.: nct6775/fan2) or labels (e.g.: "Package id 0").
+	// FanAlert is fired when any of the Fans stopped and the CPU temperature rose by FanTempDelta (°C) since then.
+	// Fans are glob patterns, CPU and chassis fans by label (or all fans if none is labeled so) are checked if empty.
+	Sensor struct {
+		Enabled      bool     `json:"enabled"`
+		Interval     Duration `json:"interval,omitempty"`
+		Slots        []string `json:"slots"`
+		FanAlert     bool     `json:"fanAlert"` // Alert when a fan stopped while CPU is heating up
+		Fans         []string `json:"fans"`
+		FanTempDelta uint     `json:"fanTempDelta"`
+	}
+
+	// Power is the CPU power measured by RAPL, daily/monthly energy is persisted in EnergyFile.
+	Power struct {
+		Enabled    bool     `json:"enabled"`
+		Interval   Duration `json:"interval,omitempty"`
+		Tariff     float64  `json:"tariff"`     // Price per kWh
+		EnergyFile string   `json:"energyFile"` // Only changeable in config file
+	}
+
+	// Custom metrics are parsed from the output of user-defined commands, reported by plugins
+	// or scraped from Prometheus endpoints, see CustomCommand, CustomPlugin and PrometheusTarget.
+	// Slots and thresholds are glob patterns matched against metric names (e.g.: queue.*).
+	// Commands, plugins and Prometheus targets are only changeable in config file.
+	Custom struct {
+		Enabled    bool               `json:"enabled"`
+		Interval   Duration           `json:"interval,omitempty"`
+		Commands   []CustomCommand    `json:"commands"`
+		Plugins    []CustomPlugin     `json:"plugins"`
+		Prometheus []PrometheusTarget `json:"prometheus"`
+		Slots      []string           `json:"slots"`
+		Thresholds []CustomThreshold  `json:"thresholds"`
+	}
+
+	// CustomCommand is run by the system shell, its output can be a plain number, key=value lines or a JSON object.
+	CustomCommand struct {
+		Name    string   `json:"name"`
+		Command string   `json:"command"`
+		Timeout Duration `json:"timeout,omitempty"` // Default to 10s
+	}
+
+	// CustomPlugin is a long-running executable which reports metrics via the protocol described in watcher/plugin.
+	CustomPlugin struct {
+		Name string   `json:"name"`
+		Path string   `json:"path"`
+		Args []string `json:"args"`
+	}
+
+	// PrometheusTarget is a Prometheus text format endpoint (e.g.: http://localhost:8080/metrics),
+	// its selected series are reported as <target name>.<series name> metrics.
+	PrometheusTarget struct {
+		Name    string             `json:"name"`
+		URL     string             `json:"url"`
+		Timeout Duration           `json:"timeout,omitempty"` // Default to 5s
+		Series  []PrometheusSeries `json:"series"`
+	}
+
+	// PrometheusSeries values of all series matched the selector are summed.
+	PrometheusSeries struct {
+		Name     string `json:"name"`
+		Selector string `json:"selector"` // E.g.: http_requests_total{code=~"5..",method!="get"}
+		Rate     bool   `json:"rate"`     // Per second rate of a counter
+	}
+
+	// Probe alert is fired when any target failed Failures times in a row.
+	Probe struct {
+		Enabled  bool          `json:"enabled"`
+		Interval Duration      `json:"interval,omitempty"`
+		Failures uint          `json:"failures"`
+		Targets  []ProbeTarget `json:"targets"` // Only changeable in config file
+	}
+
+	// ProbeTarget is probed by HTTP GET if URL is set, otherwise by connecting to the TCP Address (host:port).
+	ProbeTarget struct {
+		Name     string   `json:"name"`
+		URL      string   `json:"url,omitempty"`
+		Address  string   `json:"address,omitempty"`
+		Status   int      `json:"status,omitempty"` // Expected HTTP status code, any 2xx or 3xx if zero
+		Match    string   `json:"match,omitempty"`  // Regular expression HTTP response body must match
+		Insecure bool     `json:"insecure,omitempty"`
+		Timeout  Duration `json:"timeout,omitempty"` // Default to 5s
+	}
+
+	// Logs alert is fired when a pattern matched more lines than its threshold in the last minute.
+	Logs struct {
+		Enabled  bool         `json:"enabled"`
+		Interval Duration     `json:"interval,omitempty"`
+		Patterns []LogPattern `json:"patterns"` // Only changeable in config file
+	}
+
+	LogPattern struct {
+		Name      string `json:"name"`
+		File      string `json:"file"`
+		Pattern   string `json:"pattern"`   // Regular expression
+		Threshold uint   `json:"threshold"` // Matched lines per minute
+	}
+
+	CustomThreshold struct {
+		Metric    string  `json:"metric"`
+		Threshold float64 `json:"threshold"` // Alert when value is greater than or equal
+	}
+
+	// CgroupThreshold holds the thresholds of the groups matched the name glob pattern (container name or cgroup path).
+	CgroupThreshold struct {
+		Name             string `json:"name"`
+		CPUThreshold     uint   `json:"cpu"`     // Percent of one core
+		MemThreshold     uint   `json:"mem"`     // MB
+		MemLoadThreshold uint   `json:"memLoad"` // Percent of memory limit
+		PIDsThreshold    uint   `json:"pids"`
 	}
 )
 
@@ -101,7 +310,32 @@ func LoadFromFile(fp string) *Config {
 				NormalBrightness: 85,
 			},
 			Stats: Stats{
-				Interval: 1,
+				Interval: Duration(time.Second),
+				Network: Network{
+					Exclude: []string{"lo", "docker*", "veth*", "br-*", "virbr*"},
+				},
+				Process: Process{
+					Top: 5,
+				},
+				Kernel: Kernel{
+					OOM:           true,
+					Hardware:      true,
+					Filesystem:    true,
+					AlertDuration: Duration(5 * time.Minute),
+				},
+				Cgroup: Cgroup{
+					DockerSocket: "/var/run/docker.sock",
+				},
+				Sensor: Sensor{
+					FanAlert:     true,
+					FanTempDelta: 5,
+				},
+				Probe: Probe{
+					Failures: 3,
+				},
+				Power: Power{
+					EnergyFile: defaultEnergyFile(),
+				},
 			},
 		},
 	}
@@ -119,10 +353,20 @@ func LoadFromFile(fp string) *Config {
 	if err = json.Unmarshal(b, &cfg); err != nil {
 		logrus.Fatalf("failed to parse config file: %v", err)
 	}
+	if err = cfg.Stats.Validate(); err != nil {
+		logrus.Fatalf("config: invalid config file: %v", err)
+	}
+	if cfg.Stats.Power.EnergyFile == "" {
+		cfg.Stats.Power.EnergyFile = defaultEnergyFile()
+	}
 	logrus.Infof("config: config file %s loaded", fp)
 	return &cfg
 }
 
+func defaultEnergyFile() string {
+	return path.Join(util.GetWd(), "nights_watch_energy.json")
+}
+
 func (cfg *Config) WriteToFile(fp string) (string, error) {
 	if fp == "" {
 		fp = path.Join(util.GetWd(), "nights_watch.conf") // Default path
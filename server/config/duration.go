@@ -0,0 +1,133 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	MinInterval = 100 * time.Millisecond
+	MaxInterval = 24 * time.Hour
+	MaxTimeout  = 5 * time.Minute
+)
+
+// Duration is a time.Duration which is (un)marshaled as a human readable string (e.g.: "250ms", "5s", "1m").
+// Plain numbers are treated as seconds to stay compatible with old config files.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch val := v.(type) {
+	case float64:
+		*d = Duration(val * float64(time.Second))
+	case string:
+		td, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %s", val, err)
+		}
+		*d = Duration(td)
+	case nil:
+		*d = 0
+	default:
+		return fmt.Errorf("invalid duration: %s", string(b))
+	}
+	return nil
+}
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// IntervalOf returns the watcher interval if it's set, otherwise returns the global stats interval.
+func (s *Stats) IntervalOf(d Duration) time.Duration {
+	if d > 0 {
+		return d.Duration()
+	}
+	return s.Interval.Duration()
+}
+
+// Validate checks if the stats intervals, kernel alert duration and timeouts are in acceptable range
+// and the GPU aggregation is known or not.
+func (s *Stats) Validate() error {
+	if err := validateInterval("stats", s.Interval, false); err != nil {
+		return err
+	}
+	intervals := []struct {
+		name string
+		d    Duration
+	}{
+		{"cpu", s.CPU.Interval},
+		{"memory", s.Memory.Interval},
+		{"gpu", s.GPU.Interval},
+		{"network", s.Network.Interval},
+		{"disk", s.Disk.Interval},
+		{"process", s.Process.Interval},
+		{"psi", s.PSI.Interval},
+		{"kernel", s.Kernel.Interval},
+		{"cgroup", s.Cgroup.Interval},
+		{"battery", s.Battery.Interval},
+		{"sensor", s.Sensor.Interval},
+		{"power", s.Power.Interval},
+		{"custom", s.Custom.Interval},
+		{"probe", s.Probe.Interval},
+		{"logs", s.Logs.Interval},
+	}
+	for _, i := range intervals {
+		if err := validateInterval(i.name, i.d, true); err != nil {
+			return err
+		}
+	}
+	switch s.GPU.Aggregation {
+	case "", "max", "sum", "avg":
+	default:
+		return fmt.Errorf("gpu aggregation must be max, sum or avg, got %q", s.GPU.Aggregation)
+	}
+	if d := s.Kernel.AlertDuration.Duration(); d < time.Second || d > MaxInterval {
+		return fmt.Errorf("kernel alert duration must be between %s and %s, got %s", time.Second, MaxInterval, d)
+	}
+	for _, c := range s.Custom.Commands {
+		if err := validateTimeout("command "+c.Name, c.Timeout); err != nil {
+			return err
+		}
+	}
+	for _, p := range s.Custom.Prometheus {
+		if err := validateTimeout("prometheus target "+p.Name, p.Timeout); err != nil {
+			return err
+		}
+	}
+	for _, t := range s.Probe.Targets {
+		if err := validateTimeout("probe target "+t.Name, t.Timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateInterval(name string, d Duration, optional bool) error {
+	if optional && d == 0 {
+		return nil
+	}
+	if d.Duration() < MinInterval || d.Duration() > MaxInterval {
+		return fmt.Errorf("%s interval must be between %s and %s, got %s", name, MinInterval, MaxInterval, d.Duration())
+	}
+	return nil
+}
+
+// validateTimeout allows zero timeout which means the default one.
+func validateTimeout(name string, d Duration) error {
+	if d == 0 {
+		return nil
+	}
+	if d.Duration() < MinInterval || d.Duration() > MaxTimeout {
+		return fmt.Errorf("%s timeout must be between %s and %s, got %s", name, MinInterval, MaxTimeout, d.Duration())
+	}
+	return nil
+}
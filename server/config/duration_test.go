@@ -0,0 +1,92 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDurationUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{`2`, 2 * time.Second}, // Old config files in seconds
+		{`0.5`, 500 * time.Millisecond},
+		{`0`, 0},
+		{`"250ms"`, 250 * time.Millisecond},
+		{`"1m30s"`, 90 * time.Second},
+		{`null`, 0},
+	}
+	for _, tt := range tests {
+		d := Duration(time.Hour)
+		if err := json.Unmarshal([]byte(tt.in), &d); err != nil {
+			t.Errorf("%s: %s", tt.in, err)
+			continue
+		}
+		if d.Duration() != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.in, d.Duration(), tt.want)
+		}
+	}
+
+	for _, in := range []string{`"5"`, `"abc"`, `""`, `true`, `[1]`, `{"s": 1}`, `1s`} {
+		var d Duration
+		if err := json.Unmarshal([]byte(in), &d); err == nil {
+			t.Errorf("%s: got %s, want error", in, d.Duration())
+		}
+	}
+
+	b, err := json.Marshal(Duration(250 * time.Millisecond))
+	if err != nil || string(b) != `"250ms"` {
+		t.Errorf("got %s (error: %v), want \"250ms\"", b, err)
+	}
+}
+
+func validStats() Stats {
+	s := Stats{Interval: Duration(time.Second)}
+	s.Kernel.AlertDuration = Duration(5 * time.Minute)
+	return s
+}
+
+func TestStatsValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		set   func(s *Stats)
+		valid bool
+	}{
+		{"default", func(s *Stats) {}, true},
+		{"stats interval zero", func(s *Stats) { s.Interval = 0 }, false},
+		{"stats interval too short", func(s *Stats) { s.Interval = Duration(50 * time.Millisecond) }, false},
+		{"stats interval min", func(s *Stats) { s.Interval = Duration(MinInterval) }, true},
+		{"stats interval too long", func(s *Stats) { s.Interval = Duration(MaxInterval + time.Second) }, false},
+		{"watcher interval default", func(s *Stats) { s.CPU.Interval = 0 }, true},
+		{"watcher interval negative", func(s *Stats) { s.Disk.Interval = Duration(-time.Second) }, false},
+		{"watcher interval too long", func(s *Stats) { s.Logs.Interval = Duration(48 * time.Hour) }, false},
+		{"alert duration zero", func(s *Stats) { s.Kernel.AlertDuration = 0 }, false},
+		{"alert duration negative", func(s *Stats) { s.Kernel.AlertDuration = Duration(-time.Minute) }, false},
+		{"alert duration max", func(s *Stats) { s.Kernel.AlertDuration = Duration(MaxInterval) }, true},
+		{"alert duration too long", func(s *Stats) { s.Kernel.AlertDuration = Duration(MaxInterval + time.Second) }, false},
+		{"gpu aggregation default", func(s *Stats) { s.GPU.Aggregation = "" }, true},
+		{"gpu aggregation avg", func(s *Stats) { s.GPU.Aggregation = "avg" }, true},
+		{"gpu aggregation unknown", func(s *Stats) { s.GPU.Aggregation = "median" }, false},
+		{"gpu aggregation case", func(s *Stats) { s.GPU.Aggregation = "Sum" }, false},
+		{"timeout default", func(s *Stats) {
+			s.Custom.Commands = []CustomCommand{{Name: "queue"}}
+			s.Probe.Targets = []ProbeTarget{{Name: "web"}}
+		}, true},
+		{"command timeout", func(s *Stats) { s.Custom.Commands = []CustomCommand{{Timeout: Duration(30 * time.Second)}} }, true},
+		{"command timeout negative", func(s *Stats) { s.Custom.Commands = []CustomCommand{{Timeout: Duration(-time.Second)}} }, false},
+		{"command timeout too long", func(s *Stats) { s.Custom.Commands = []CustomCommand{{Timeout: Duration(time.Hour)}} }, false},
+		{"prometheus timeout too short", func(s *Stats) { s.Custom.Prometheus = []PrometheusTarget{{Timeout: Duration(time.Millisecond)}} }, false},
+		{"prometheus timeout max", func(s *Stats) { s.Custom.Prometheus = []PrometheusTarget{{Timeout: Duration(MaxTimeout)}} }, true},
+		{"probe timeout negative", func(s *Stats) { s.Probe.Targets = []ProbeTarget{{}, {Timeout: Duration(-time.Second)}} }, false},
+		{"probe timeout too long", func(s *Stats) { s.Probe.Targets = []ProbeTarget{{Timeout: Duration(MaxTimeout + time.Second)}} }, false},
+	}
+	for _, tt := range tests {
+		s := validStats()
+		tt.set(&s)
+		if err := s.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: got error %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
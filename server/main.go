@@ -68,6 +68,10 @@ func main() {
 			r.Get("/admin", handler.GetAdminConfig)
 			r.Post("/admin", handler.UpdateAdminConfig)
 		})
+		r.Route("/stats", func(r chi.Router) {
+			r.Use(handler.Authentication)
+			r.Get("/", handler.GetStats)
+		})
 		r.Route("/dev", func(r chi.Router) {
 			r.Get("/tmpl/reload", handler.ReloadTemplate)
 		})
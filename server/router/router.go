@@ -14,10 +14,23 @@ import (
 	"github.com/go-chi/render"
 	"github.com/lnquy/nights-watch/server/config"
 	"github.com/lnquy/nights-watch/server/util"
+	"github.com/lnquy/nights-watch/server/watcher/battery"
+	"github.com/lnquy/nights-watch/server/watcher/cgroup"
 	"github.com/lnquy/nights-watch/server/watcher/cpu"
+	"github.com/lnquy/nights-watch/server/watcher/custom"
+	"github.com/lnquy/nights-watch/server/watcher/disk"
 	"github.com/lnquy/nights-watch/server/watcher/gpu"
+	"github.com/lnquy/nights-watch/server/watcher/kernel"
+	"github.com/lnquy/nights-watch/server/watcher/logs"
 	"github.com/lnquy/nights-watch/server/watcher/mem"
 	"github.com/lnquy/nights-watch/server/watcher/net"
+	"github.com/lnquy/nights-watch/server/watcher/plugin"
+	"github.com/lnquy/nights-watch/server/watcher/power"
+	"github.com/lnquy/nights-watch/server/watcher/probe"
+	"github.com/lnquy/nights-watch/server/watcher/proc"
+	"github.com/lnquy/nights-watch/server/watcher/prometheus"
+	"github.com/lnquy/nights-watch/server/watcher/psi"
+	"github.com/lnquy/nights-watch/server/watcher/sensor"
 	"github.com/sirupsen/logrus"
 	"github.com/tarm/serial"
 )
@@ -30,6 +43,7 @@ type (
 		cancel      context.CancelFunc
 		sleepCtx    context.Context
 		sleepCancel context.CancelFunc
+		stats       *latestStats
 	}
 
 	login struct {
@@ -53,6 +67,16 @@ const (
 	atMemory
 	atGPU
 	atNetwork
+	atDisk
+	atPSI
+	atKernel
+	atCgroup
+	atBattery
+	atSensor
+	atThrottle
+	atCustom
+	atProbe
+	atLogs
 )
 
 var (
@@ -79,6 +103,7 @@ func New(cfg *config.Config) *Router {
 		sConn:  newSerialConn(*cfg),
 		ctx:    ctx,
 		cancel: cancel,
+		stats:  &latestStats{},
 	}
 	if r.sConn != nil {
 		r.sleepTimer()
@@ -110,96 +135,398 @@ func newSerialConn(cfg config.Config) *serial.Port {
 // 2: Memory stats
 // 3: GPU stats
 // 4: Network stats
+// 5: Disk stats
+// 6: Top GPU process
+// 7: Top processes
+// 8: PSI stats
+// 9: Kernel event
+// b: Battery stats
+// c: Top cgroup/container
+// e: CPU power and energy cost
+// h: Sensor display slot
+// l: Log pattern matches
+// p: Probe stats
+// u: Custom metric display slot
+// y: Brightness
 // z: Alert
 func (rt *Router) watchStats() {
-	interval := time.Duration(rt.cfg.Stats.Interval) * time.Second
+	st := &rt.cfg.Stats
 	rt.sConn.Write([]byte(fmt.Sprintf("y|%d$", rt.cfg.Sleep.NormalBrightness)))
 
 	// Reset all old stats/alerts then init new watchers
+	rt.stats.reset()
 	cw := make(<-chan *cpu.Stats)
 	rt.sConn.Write([]byte("1|-|-$"))
 	rt.sConn.Write([]byte("z|1|0$"))
+	rt.sConn.Write([]byte("z|11|0$"))
 	if rt.cfg.Stats.CPU.Enabled {
-		cw = cpu.NewWatcher().GetStats(rt.ctx, interval)
+		cw = cpu.NewWatcher().GetStats(rt.ctx, st.IntervalOf(st.CPU.Interval))
 	}
 
 	mw := make(<-chan *mem.Stats)
 	rt.sConn.Write([]byte("2|-|-$"))
 	rt.sConn.Write([]byte("z|2|0$"))
 	if rt.cfg.Stats.Memory.Enabled {
-		mw = mem.NewWatcher().GetStats(rt.ctx, interval)
+		mw = mem.NewWatcher().GetStats(rt.ctx, st.IntervalOf(st.Memory.Interval))
 	}
 
 	gw := make(<-chan *gpu.Stats)
 	rt.sConn.Write([]byte("3|-|-$"))
 	rt.sConn.Write([]byte("z|3|0$"))
+	rt.sConn.Write([]byte("6|-|-$"))
 	if rt.cfg.Stats.GPU.Enabled {
-		if rt.cfg.Stats.GPU.Vendor == "" || rt.cfg.Stats.GPU.Vendor == string(gpu.NVIDIA) {
-			gw = gpu.NewWatcher().GetStats(rt.ctx, interval, gpu.NVIDIA)
+		vendor := gpu.GPUVendor(rt.cfg.Stats.GPU.Vendor)
+		if vendor == "" {
+			vendor = gpu.NVIDIA
 		}
-		// TODO: Other vendors
+		ids := make([]string, 0, len(st.GPU.Cards))
+		for _, c := range st.GPU.Cards {
+			ids = append(ids, c.ID)
+		}
+		gw = gpu.NewWatcher(ids, gpu.Aggregation(st.GPU.Aggregation), st.GPU.Processes).GetStats(rt.ctx, st.IntervalOf(st.GPU.Interval), vendor)
 	}
 
 	nw := make(<-chan *net.Stats)
 	rt.sConn.Write([]byte("4|-|-$"))
 	rt.sConn.Write([]byte("z|4|0$"))
 	if rt.cfg.Stats.Network.Enabled {
-		nw = net.NewWatcher().GetStats(rt.ctx, interval)
+		nw = net.NewWatcher(st.Network.Include, st.Network.Exclude).GetStats(rt.ctx, st.IntervalOf(st.Network.Interval))
+	}
+
+	dw := make(<-chan *disk.Stats)
+	rt.sConn.Write([]byte("5|-|-$"))
+	rt.sConn.Write([]byte("z|5|0$"))
+	if rt.cfg.Stats.Disk.Enabled {
+		dw = disk.NewWatcher(st.Disk.Include, st.Disk.Exclude).GetStats(rt.ctx, st.IntervalOf(st.Disk.Interval))
+	}
+
+	pw := make(<-chan *proc.Stats)
+	rt.sConn.Write([]byte("7|-|-|-|-$"))
+	if rt.cfg.Stats.Process.Enabled {
+		pw = proc.NewWatcher(st.Process.Top).GetStats(rt.ctx, st.IntervalOf(st.Process.Interval))
+	}
+
+	sw := make(<-chan *psi.Stats)
+	rt.sConn.Write([]byte("8|-|-|-$"))
+	rt.sConn.Write([]byte("z|6|0$"))
+	if rt.cfg.Stats.PSI.Enabled {
+		sw = psi.NewWatcher().GetStats(rt.ctx, st.IntervalOf(st.PSI.Interval))
+	}
+
+	kw := make(<-chan *kernel.Stats)
+	rt.sConn.Write([]byte("9|-|-$"))
+	rt.sConn.Write([]byte("z|7|0$"))
+	if rt.cfg.Stats.Kernel.Enabled {
+		kw = kernel.NewWatcher().GetStats(rt.ctx, st.IntervalOf(st.Kernel.Interval))
+	}
+	var lastKernelEvent time.Time
+
+	gcw := make(<-chan *cgroup.Stats)
+	rt.sConn.Write([]byte("c|-|-|-$"))
+	rt.sConn.Write([]byte("z|8|0$"))
+	if rt.cfg.Stats.Cgroup.Enabled {
+		socket := ""
+		if st.Cgroup.Docker {
+			socket = st.Cgroup.DockerSocket
+		}
+		gcw = cgroup.NewWatcher(st.Cgroup.Paths, socket).GetStats(rt.ctx, st.IntervalOf(st.Cgroup.Interval))
+	}
+
+	bw := make(<-chan *battery.Stats)
+	rt.sConn.Write([]byte("b|-|-$"))
+	rt.sConn.Write([]byte("z|9|0$"))
+	if rt.cfg.Stats.Battery.Enabled {
+		bw = battery.NewWatcher().GetStats(rt.ctx, st.IntervalOf(st.Battery.Interval))
+	}
+	dimmed := false // LCD is dimmed while on battery
+
+	hw := make(<-chan *sensor.Stats)
+	for i := range st.Sensor.Slots {
+		rt.sConn.Write([]byte(fmt.Sprintf("h|%d|-|-$", i)))
+	}
+	rt.sConn.Write([]byte("z|10|0$"))
+	if rt.cfg.Stats.Sensor.Enabled {
+		hw = sensor.NewWatcher(st.Sensor.Fans, st.Sensor.FanTempDelta).GetStats(rt.ctx, st.IntervalOf(st.Sensor.Interval))
+	}
+
+	ew := make(<-chan *power.Stats)
+	rt.sConn.Write([]byte("e|-|-|-$"))
+	if rt.cfg.Stats.Power.Enabled {
+		ew = power.NewWatcher(st.Power.EnergyFile, st.Power.Tariff).GetStats(rt.ctx, st.IntervalOf(st.Power.Interval))
+	}
+
+	uw := make(<-chan *custom.Stats)
+	for i := range st.Custom.Slots {
+		rt.sConn.Write([]byte(fmt.Sprintf("u|%d|-|-$", i)))
+	}
+	rt.sConn.Write([]byte("z|12|0$"))
+	if rt.cfg.Stats.Custom.Enabled {
+		cmds := make([]custom.Command, 0, len(st.Custom.Commands))
+		for _, c := range st.Custom.Commands {
+			cmds = append(cmds, custom.Command{Name: c.Name, Command: c.Command, Timeout: c.Timeout.Duration()})
+		}
+		uw = custom.NewWatcher(cmds).GetStats(rt.ctx, st.IntervalOf(st.Custom.Interval))
+	}
+	pgw := make(<-chan *plugin.Stats)
+	if rt.cfg.Stats.Custom.Enabled && len(st.Custom.Plugins) > 0 {
+		plugins := make([]plugin.Plugin, 0, len(st.Custom.Plugins))
+		for _, p := range st.Custom.Plugins {
+			plugins = append(plugins, plugin.Plugin{Name: p.Name, Path: p.Path, Args: p.Args})
+		}
+		pgw = plugin.NewWatcher(plugins).GetStats(rt.ctx, st.IntervalOf(st.Custom.Interval))
+	}
+	pmw := make(<-chan *prometheus.Stats)
+	if rt.cfg.Stats.Custom.Enabled && len(st.Custom.Prometheus) > 0 {
+		targets := make([]prometheus.Target, 0, len(st.Custom.Prometheus))
+		for _, t := range st.Custom.Prometheus {
+			pt := prometheus.Target{Name: t.Name, URL: t.URL, Timeout: t.Timeout.Duration()}
+			for _, s := range t.Series {
+				pt.Series = append(pt.Series, prometheus.Series{Name: s.Name, Selector: s.Selector, Rate: s.Rate})
+			}
+			targets = append(targets, pt)
+		}
+		pmw = prometheus.NewWatcher(targets).GetStats(rt.ctx, st.IntervalOf(st.Custom.Interval))
+	}
+	// Custom metrics are displayed and alerted together
+	var commandMetrics, pluginMetrics, promMetrics []custom.Metric
+
+	prw := make(<-chan *probe.Stats)
+	rt.sConn.Write([]byte("p|-|-$"))
+	rt.sConn.Write([]byte("z|13|0$"))
+	if rt.cfg.Stats.Probe.Enabled {
+		targets := make([]probe.Target, 0, len(st.Probe.Targets))
+		for _, t := range st.Probe.Targets {
+			targets = append(targets, probe.Target{
+				Name:     t.Name,
+				URL:      t.URL,
+				Address:  t.Address,
+				Status:   t.Status,
+				Match:    t.Match,
+				Insecure: t.Insecure,
+				Timeout:  t.Timeout.Duration(),
+			})
+		}
+		prw = probe.NewWatcher(targets).GetStats(rt.ctx, st.IntervalOf(st.Probe.Interval))
 	}
 
-	// Flags holds current alert status (ON/OFF)
-	cwa, mwa, gwa, nwa := false, false, false, false
-	// Flags holds alert status of each alert threshold
-	cwParms, mwParms, gwParms, nwParms := make([]bool, 2), make([]bool, 1), make([]bool, 2), make([]bool, 2)
+	lw := make(<-chan *logs.Stats)
+	rt.sConn.Write([]byte("l|-|-|-$"))
+	rt.sConn.Write([]byte("z|14|0$"))
+	if rt.cfg.Stats.Logs.Enabled {
+		patterns := make([]logs.Pattern, 0, len(st.Logs.Patterns))
+		for _, p := range st.Logs.Patterns {
+			patterns = append(patterns, logs.Pattern{Name: p.Name, File: p.File, Pattern: p.Pattern})
+		}
+		lw = logs.NewWatcher(patterns).GetStats(rt.ctx, st.IntervalOf(st.Logs.Interval))
+	}
+
+	// Alert status of each watcher and its thresholds
+	cwa, mwa, nwa, dwa := newWatcherAlert(atCPU, 2), newWatcherAlert(atMemory, 4), newWatcherAlert(atNetwork, 5), newWatcherAlert(atDisk, 3)
+	gwa := newWatcherAlert(atGPU, 4+4*len(st.GPU.Cards)) // Aggregated and per card thresholds
+	swa, kwa, gcwa, bwa := newWatcherAlert(atPSI, 4), newWatcherAlert(atKernel, 1), newWatcherAlert(atCgroup, 4), newWatcherAlert(atBattery, 2)
+	hwa, twa, prwa := newWatcherAlert(atSensor, 1), newWatcherAlert(atThrottle, 1), newWatcherAlert(atProbe, 1)
+	uwa, lwa := newWatcherAlert(atCustom, len(st.Custom.Thresholds)), newWatcherAlert(atLogs, len(st.Logs.Patterns))
 	for {
 		select {
 		case s := <-cw:
 			if s == nil {
 				continue
 			}
+			checkThreshold(rt.cfg.Stats.CPU.LoadThreshold, uint(s.Load), cwa.parms, 0)
+			checkThreshold(rt.cfg.Stats.CPU.TempThreshold, uint(s.Temp), cwa.parms, 1)
 			cmd := fmt.Sprintf("1|%.0f|%.0f$", s.Load, s.Temp)
-			logrus.Debugf("CPU: %s", cmd)
-			if _, err := rt.sConn.Write([]byte(cmd)); err != nil {
-				logrus.Errorf("CPU: failed to write stats to Arduino: %s", cmd)
+			cwa.detail = rt.topProcess("CPU")
+			if rt.report("CPU", cmd, func(ls *latestStats) { c := *s; ls.CPU = &c }, cwa) {
+				rt.recordTopProcesses("CPU")
+			}
+			// Throttling is alerted separately since high temperature only matters when it slows down the CPU
+			twa.parms[0] = rt.cfg.Stats.CPU.ThrottleAlert && s.Throttle.Throttled
+			if twa.check(rt.sConn) {
+				logrus.Warnf("CPU: throttled (%s) at %.0f°C, %.0f/%.0fMHz", strings.Join(s.Throttle.Reasons, ", "),
+					s.Temp, s.Throttle.Frequency, s.Throttle.BaseFrequency)
 			}
-			checkThreshold(rt.cfg.Stats.CPU.LoadThreshold, uint(s.Load), cwParms, 0)
-			checkThreshold(rt.cfg.Stats.CPU.TempThreshold, uint(s.Temp), cwParms, 1)
-			alert(rt.sConn, cwParms, &cwa, atCPU)
 		case s := <-mw:
 			if s == nil {
 				continue
 			}
+			checkThreshold(rt.cfg.Stats.Memory.LoadThreshold, uint(s.Load), mwa.parms, 0)
+			checkThreshold(rt.cfg.Stats.Memory.SwapThreshold, uint(s.SwapLoad), mwa.parms, 1)
+			checkThreshold(rt.cfg.Stats.Memory.DirtyThreshold, uint(s.Dirty), mwa.parms, 2)
+			checkThreshold(rt.cfg.Stats.Memory.FaultThreshold, uint(s.MajorFaults), mwa.parms, 3)
 			cmd := fmt.Sprintf("2|%.0f|%d$", s.Load, s.Usage)
-			logrus.Debugf("MEM: %s", cmd)
-			if _, err := rt.sConn.Write([]byte(cmd)); err != nil {
-				logrus.Errorf("MEM: failed to write stats to Arduino: %s", cmd)
+			mwa.detail = rt.topProcess("MEM")
+			if rt.report("MEM", cmd, func(ls *latestStats) { c := *s; ls.Memory = &c }, mwa) {
+				rt.recordTopProcesses("MEM")
 			}
-			checkThreshold(rt.cfg.Stats.Memory.LoadThreshold, uint(s.Load), mwParms, 0)
-			alert(rt.sConn, mwParms, &mwa, atMemory)
 		case s := <-gw:
 			if s == nil {
 				continue
 			}
+			if !s.Available { // Turn off alert as stats are unknown
+				gwa.reset()
+				rt.report("GPU", "3|N/A|N/A$", func(ls *latestStats) { c := *s; ls.GPU = &c }, gwa)
+				continue
+			}
+			checkThreshold(rt.cfg.Stats.GPU.LoadThreshold, uint(s.Load), gwa.parms, 0)
+			checkThreshold(rt.cfg.Stats.GPU.MemThreshold, uint(s.Mem), gwa.parms, 1)
+			checkThreshold(rt.cfg.Stats.GPU.TempThreshold, uint(s.Temp), gwa.parms, 2)
+			checkThreshold(rt.cfg.Stats.GPU.PowerThreshold, uint(s.Power), gwa.parms, 3)
+			checkCardThresholds(st.GPU.Cards, s.Cards, gwa.parms[4:])
 			cmd := fmt.Sprintf("3|%.0f|%d$", s.Load, s.Mem)
-			logrus.Debugf("GPU: %s", cmd)
-			if _, err := rt.sConn.Write([]byte(cmd)); err != nil {
-				logrus.Errorf("GPU: failed to write stats to Arduino: %s", cmd)
+			rt.report("GPU", cmd, func(ls *latestStats) { c := *s; ls.GPU = &c }, gwa)
+			if st.GPU.Processes {
+				rt.writeStats("GPU", topGPUProcessCmd(s.Cards))
 			}
-			checkThreshold(rt.cfg.Stats.GPU.LoadThreshold, uint(s.Load), gwParms, 0)
-			checkThreshold(rt.cfg.Stats.GPU.MemThreshold, uint(s.Mem), gwParms, 1)
-			alert(rt.sConn, gwParms, &gwa, atGPU)
 		case s := <-nw:
 			if s == nil {
 				continue
 			}
+			checkThreshold(rt.cfg.Stats.Network.DownloadThreshold, uint(s.Download), nwa.parms, 0)
+			checkThreshold(rt.cfg.Stats.Network.UploadThreshold, uint(s.Upload), nwa.parms, 1)
+			checkThreshold(rt.cfg.Stats.Network.ErrorThreshold, uint(s.Errors), nwa.parms, 2)
+			checkThreshold(rt.cfg.Stats.Network.DropThreshold, uint(s.Drops), nwa.parms, 3)
+			// Retransmit rate is usually a fraction of percent, so it's not truncated to uint
+			nwa.parms[4] = rt.cfg.Stats.Network.RetransmitThreshold > 0 && s.TCP.RetransmitRate >= rt.cfg.Stats.Network.RetransmitThreshold
 			cmd := fmt.Sprintf("4|%d|%d$", s.Download, s.Upload)
-			logrus.Debugf("NET: %s", cmd)
-			if _, err := rt.sConn.Write([]byte(cmd)); err != nil {
-				logrus.Errorf("NET: failed to write stats to Arduino: %s", cmd)
+			rt.report("NET", cmd, func(ls *latestStats) { c := *s; ls.Network = &c }, nwa)
+		case s := <-dw:
+			if s == nil {
+				continue
+			}
+			checkThreshold(rt.cfg.Stats.Disk.LoadThreshold, uint(s.Load), dwa.parms, 0)
+			checkThreshold(rt.cfg.Stats.Disk.InodesThreshold, uint(s.InodesLoad), dwa.parms, 1)
+			checkThreshold(rt.cfg.Stats.Disk.LatencyThreshold, uint(s.Latency), dwa.parms, 2)
+			cmd := fmt.Sprintf("5|%.0f|%d$", s.Load, s.Free/1000) // GB
+			rt.report("DISK", cmd, func(ls *latestStats) { c := *s; ls.Disk = &c }, dwa)
+		case s := <-pw:
+			if s == nil {
+				continue
+			}
+			rt.report("PROC", topProcessesCmd(s), func(ls *latestStats) { c := *s; ls.Process = &c }, nil)
+		case s := <-sw:
+			if s == nil {
+				continue
+			}
+			if !s.Available {
+				rt.report("PSI", "8|N/A|N/A|N/A$", func(ls *latestStats) { c := *s; ls.PSI = &c }, nil)
+				continue
 			}
-			checkThreshold(rt.cfg.Stats.Network.DownloadThreshold, uint(s.Download), nwParms, 0)
-			checkThreshold(rt.cfg.Stats.Network.UploadThreshold, uint(s.Upload), nwParms, 1)
-			alert(rt.sConn, nwParms, &nwa, atNetwork)
+			checkThreshold(rt.cfg.Stats.PSI.CPUThreshold, uint(s.CPU.Some.Avg10), swa.parms, 0)
+			checkThreshold(rt.cfg.Stats.PSI.MemoryThreshold, uint(s.Memory.Some.Avg10), swa.parms, 1)
+			checkThreshold(rt.cfg.Stats.PSI.MemoryFullThreshold, uint(s.Memory.Full.Avg10), swa.parms, 2)
+			checkThreshold(rt.cfg.Stats.PSI.IOThreshold, uint(s.IO.Some.Avg10), swa.parms, 3)
+			cmd := fmt.Sprintf("8|%.0f|%.0f|%.0f$", s.CPU.Some.Avg10, s.Memory.Some.Avg10, s.IO.Some.Avg10)
+			rt.report("PSI", cmd, func(ls *latestStats) { c := *s; ls.PSI = &c }, swa)
+		case s := <-kw:
+			if s == nil {
+				continue
+			}
+			for _, e := range s.Events {
+				logrus.Warnf("KERNEL: %s event detected (process: %s, pid: %d): %s", e.Type, e.Process, e.PID, e.Message)
+				if !isKernelEventAlerted(st.Kernel, e.Type) {
+					continue
+				}
+				lastKernelEvent = e.Time
+				rt.writeStats("KERNEL", fmt.Sprintf("9|%s|%s$", e.Type, escapeSerial(e.Process)))
+			}
+			// Events are discrete so the alert is kept for a while after the last event
+			kwa.parms[0] = !lastKernelEvent.IsZero() && time.Since(lastKernelEvent) < st.Kernel.AlertDuration.Duration()
+			rt.report("KERNEL", "", func(ls *latestStats) { c := *s; ls.Kernel = &c }, kwa)
+		case s := <-gcw:
+			if s == nil {
+				continue
+			}
+			checkCgroupThresholds(st.Cgroup.Thresholds, s.Groups, gcwa.parms)
+			rt.report("CGROUP", topCgroupCmd(s.Groups), func(ls *latestStats) { c := *s; ls.Cgroup = &c }, gcwa)
+		case s := <-bw:
+			if s == nil {
+				continue
+			}
+			// Power source changed: dim the LCD when AC is unplugged and restore it when plugged back
+			if st.Battery.SleepOnBattery && s.Present && s.ACOnline == dimmed {
+				dimmed = !s.ACOnline
+				brightness := rt.cfg.Sleep.NormalBrightness
+				if dimmed {
+					brightness = rt.cfg.Sleep.SleepBrightness
+				}
+				logrus.Infof("BATTERY: AC online: %v, set LCD brightness to %d", s.ACOnline, brightness)
+				rt.sConn.Write([]byte(fmt.Sprintf("y|%d$", brightness)))
+			}
+			// Battery level and health are low when below thresholds
+			bwa.parms[0] = st.Battery.LowThreshold > 0 && s.Present && !s.ACOnline && s.Percent < float64(st.Battery.LowThreshold)
+			bwa.parms[1] = st.Battery.HealthThreshold > 0 && s.Health > 0 && s.Health < float64(st.Battery.HealthThreshold)
+			cmd := fmt.Sprintf("b|%.0f|%s$", s.Percent, s.Status)
+			if !s.Present {
+				cmd = "b|N/A|N/A$"
+			}
+			rt.report("BATTERY", cmd, func(ls *latestStats) { c := *s; ls.Battery = &c }, bwa)
+		case s := <-hw:
+			if s == nil {
+				continue
+			}
+			for i, slot := range st.Sensor.Slots {
+				rt.writeStats("SENSOR", sensorSlotCmd(i, slot, s.Sensors))
+			}
+			if len(s.StoppedFans) > 0 {
+				logrus.Warnf("SENSOR: fans stopped while CPU is heating up (%.0f°C): %s", s.CPUTemp, strings.Join(s.StoppedFans, ", "))
+			}
+			hwa.parms[0] = st.Sensor.FanAlert && len(s.StoppedFans) > 0
+			rt.report("SENSOR", "", func(ls *latestStats) { c := *s; ls.Sensor = &c }, hwa)
+		case s := <-ew:
+			if s == nil {
+				continue
+			}
+			cmd := fmt.Sprintf("e|%.0f|%.2f|%.2f$", s.Package+s.DRAM, s.EnergyToday, s.CostToday)
+			rt.report("POWER", cmd, func(ls *latestStats) { c := *s; ls.Power = &c }, nil)
+		case s := <-uw:
+			if s == nil {
+				continue
+			}
+			rt.stats.update(func(ls *latestStats) { c := *s; ls.Custom = &c })
+			commandMetrics = s.Metrics
+			rt.writeCustomMetrics(joinMetrics(commandMetrics, pluginMetrics, promMetrics), uwa)
+		case s := <-pgw:
+			if s == nil {
+				continue
+			}
+			rt.stats.update(func(ls *latestStats) { c := *s; ls.Plugin = &c })
+			pluginMetrics = s.Metrics
+			rt.writeCustomMetrics(joinMetrics(commandMetrics, pluginMetrics, promMetrics), uwa)
+		case s := <-pmw:
+			if s == nil {
+				continue
+			}
+			rt.stats.update(func(ls *latestStats) { c := *s; ls.Prometheus = &c })
+			promMetrics = s.Metrics
+			rt.writeCustomMetrics(joinMetrics(commandMetrics, pluginMetrics, promMetrics), uwa)
+		case s := <-prw:
+			if s == nil {
+				continue
+			}
+			failures := st.Probe.Failures
+			if failures == 0 {
+				failures = 1
+			}
+			prwa.parms[0] = false
+			for _, t := range s.Targets {
+				if !t.Up && t.Failures >= failures {
+					prwa.parms[0] = true
+				}
+				if !t.Up && t.Failures == failures {
+					logrus.Warnf("PROBE: %s is down: %s", t.Name, t.Error)
+				}
+			}
+			rt.report("PROBE", probeCmd(s.Targets), func(ls *latestStats) { c := *s; ls.Probe = &c }, prwa)
+		case s := <-lw:
+			if s == nil {
+				continue
+			}
+			// Patterns are in the same order as configured
+			for i, p := range s.Patterns {
+				threshold := st.Logs.Patterns[i].Threshold
+				lwa.parms[i] = threshold > 0 && p.PerMinute > uint64(threshold)
+			}
+			rt.report("LOGS", topLogPatternCmd(s.Patterns), func(ls *latestStats) { c := *s; ls.Logs = &c }, lwa)
 		case <-rt.ctx.Done():
 			// TODO
 			return
@@ -207,6 +534,27 @@ func (rt *Router) watchStats() {
 	}
 }
 
+// report updates the latest stats served by API, writes the stats command to Arduino (if any)
+// and then the alert status by its threshold flags (if any).
+// Returns true if the alert has just been turned on.
+func (rt *Router) report(watcher, cmd string, update func(ls *latestStats), a *watcherAlert) bool {
+	rt.stats.update(update)
+	if cmd != "" {
+		rt.writeStats(watcher, cmd)
+	}
+	if a == nil {
+		return false
+	}
+	return a.check(rt.sConn)
+}
+
+func (rt *Router) writeStats(watcher, cmd string) {
+	logrus.Debugf("%s: %s", watcher, cmd)
+	if _, err := rt.sConn.Write([]byte(cmd)); err != nil {
+		logrus.Errorf("%s: failed to write stats to Arduino: %s", watcher, cmd)
+	}
+}
+
 func (rt *Router) Stop(stopSleepContext bool) {
 	if rt.cancel != nil {
 		rt.cancel()
@@ -228,11 +576,273 @@ func checkThreshold(threshold, value uint, parms []bool, idx int) {
 	}
 }
 
-func alert(sConn *serial.Port, parms []bool, flag *bool, at alertType) {
+// checkCardThresholds checks thresholds of each configured GPU card, 4 flags per card.
+func checkCardThresholds(cfgCards []config.GPUCard, cards []gpu.Card, parms []bool) {
+	for i, cc := range cfgCards {
+		p := parms[4*i : 4*i+4]
+		p[0], p[1], p[2], p[3] = false, false, false, false
+		for _, c := range cards {
+			if c.Match(cc.ID) {
+				checkThreshold(cc.LoadThreshold, uint(c.Load), p, 0)
+				checkThreshold(cc.MemThreshold, uint(c.Mem), p, 1)
+				checkThreshold(cc.TempThreshold, uint(c.Temp), p, 2)
+				checkThreshold(cc.PowerThreshold, uint(c.Power), p, 3)
+				break
+			}
+		}
+	}
+}
+
+// checkCgroupThresholds checks thresholds of all groups, each flag is set if any group reached the threshold.
+func checkCgroupThresholds(thresholds []config.CgroupThreshold, groups []cgroup.Group, parms []bool) {
+	for i := range parms {
+		parms[i] = false
+	}
+	p := make([]bool, len(parms))
+	for _, t := range thresholds {
+		for _, g := range groups {
+			if !g.Match(t.Name) {
+				continue
+			}
+			checkThreshold(t.CPUThreshold, uint(g.CPU), p, 0)
+			checkThreshold(t.MemThreshold, uint(g.MemUsage), p, 1)
+			checkThreshold(t.MemLoadThreshold, uint(g.MemLoad), p, 2)
+			checkThreshold(t.PIDsThreshold, uint(g.PIDs), p, 3)
+			for i := range parms {
+				parms[i] = parms[i] || p[i]
+			}
+		}
+	}
+}
+
+// topCgroupCmd returns the command to display the group using the most CPU.
+func topCgroupCmd(groups []cgroup.Group) string {
+	var top *cgroup.Group
+	for i := range groups {
+		if top == nil || groups[i].CPU > top.CPU {
+			top = &groups[i]
+		}
+	}
+	if top == nil {
+		return "c|-|-|-$"
+	}
+	return fmt.Sprintf("c|%s|%.0f|%d$", escapeSerial(top.Name), top.CPU, top.MemUsage)
+}
+
+// sensorSlotCmd returns the command to display the first sensor matched the slot pattern.
+func sensorSlotCmd(idx int, slot string, sensors []sensor.Sensor) string {
+	for _, s := range sensors {
+		if !s.Match(slot) {
+			continue
+		}
+		prec, unit := 1, s.Unit
+		switch s.Type {
+		case sensor.Fan:
+			prec = 0
+		case sensor.Voltage:
+			prec = 2
+		case sensor.Temperature:
+			unit = "*C" // LCD font has no degree sign
+		}
+		return fmt.Sprintf("h|%d|%s|%.*f%s$", idx, escapeSerial(s.Label), prec, s.Value, unit)
+	}
+	return fmt.Sprintf("h|%d|-|-$", idx)
+}
+
+// writeCustomMetrics displays the custom metrics on slots and alerts when they reached thresholds.
+func (rt *Router) writeCustomMetrics(metrics []custom.Metric, a *watcherAlert) {
+	for i, slot := range rt.cfg.Stats.Custom.Slots {
+		rt.writeStats("CUSTOM", customSlotCmd(i, slot, metrics))
+	}
+	checkCustomThresholds(rt.cfg.Stats.Custom.Thresholds, metrics, a.parms)
+	a.check(rt.sConn)
+}
+
+func joinMetrics(metrics ...[]custom.Metric) []custom.Metric {
+	joined := make([]custom.Metric, 0)
+	for _, m := range metrics {
+		joined = append(joined, m...)
+	}
+	return joined
+}
+
+// customSlotCmd returns the command to display the first metric matched the slot pattern.
+func customSlotCmd(idx int, slot string, metrics []custom.Metric) string {
+	for _, m := range metrics {
+		if ok, _ := path.Match(slot, m.Name); ok {
+			return fmt.Sprintf("u|%d|%s|%s$", idx, escapeSerial(m.Name), strconv.FormatFloat(m.Value, 'f', -1, 64))
+		}
+	}
+	return fmt.Sprintf("u|%d|-|-$", idx)
+}
+
+// checkCustomThresholds sets the flag of each threshold if any metric matched its pattern reached it.
+func checkCustomThresholds(thresholds []config.CustomThreshold, metrics []custom.Metric, parms []bool) {
+	for i, t := range thresholds {
+		parms[i] = false
+		for _, m := range metrics {
+			if ok, _ := path.Match(t.Metric, m.Name); ok && t.Threshold > 0 && m.Value >= t.Threshold {
+				parms[i] = true
+				break
+			}
+		}
+	}
+}
+
+// probeCmd returns the command to display the number of up targets and the first down target.
+func probeCmd(targets []probe.Result) string {
+	up, down := 0, "-"
+	for _, t := range targets {
+		if t.Up {
+			up++
+		} else if down == "-" {
+			down = escapeSerial(t.Name)
+		}
+	}
+	return fmt.Sprintf("p|%d/%d|%s$", up, len(targets), down)
+}
+
+// topLogPatternCmd returns the command to display the pattern matched the most lines in the last minute.
+func topLogPatternCmd(patterns []logs.Match) string {
+	var top *logs.Match
+	for i := range patterns {
+		if patterns[i].PerMinute > 0 && (top == nil || patterns[i].PerMinute > top.PerMinute) {
+			top = &patterns[i]
+		}
+	}
+	if top == nil {
+		return "l|-|0|-$"
+	}
+	line := util.Truncate(top.LastLine, 40) // LCD text field is short
+	return fmt.Sprintf("l|%s|%d|%s$", escapeSerial(top.Name), top.PerMinute, escapeSerial(line))
+}
+
+func isKernelEventAlerted(cfg config.Kernel, typ string) bool {
+	switch typ {
+	case kernel.OOM:
+		return cfg.OOM
+	case kernel.Hardware:
+		return cfg.Hardware
+	case kernel.Filesystem:
+		return cfg.Filesystem
+	case kernel.Segfault:
+		return cfg.Segfault
+	}
+	return false
+}
+
+// topProcessesCmd returns the command to display the top CPU and memory consuming processes.
+func topProcessesCmd(s *proc.Stats) string {
+	cpuName, cpuLoad, memName, memUsage := "-", "-", "-", "-"
+	if len(s.ByCPU) > 0 {
+		cpuName, cpuLoad = escapeSerial(s.ByCPU[0].Name), fmt.Sprintf("%.0f", s.ByCPU[0].CPU)
+	}
+	if len(s.ByMemory) > 0 {
+		memName, memUsage = escapeSerial(s.ByMemory[0].Name), strconv.FormatUint(s.ByMemory[0].Memory, 10)
+	}
+	return fmt.Sprintf("7|%s|%s|%s|%s$", cpuName, cpuLoad, memName, memUsage)
+}
+
+// topProcesses returns the latest top processes by CPU or memory usage of the watcher.
+func (rt *Router) topProcesses(watcher string) []proc.Process {
+	rt.stats.mu.RLock()
+	defer rt.stats.mu.RUnlock()
+	if rt.stats.Process == nil {
+		return nil
+	}
+	procs := rt.stats.Process.ByCPU
+	if watcher == "MEM" {
+		procs = rt.stats.Process.ByMemory
+	}
+	return append([]proc.Process(nil), procs...)
+}
+
+// topProcess returns the process using the most CPU or memory, which is sent along with the alert
+// so the likely culprit is displayed on LCD.
+func (rt *Router) topProcess(watcher string) string {
+	procs := rt.topProcesses(watcher)
+	if len(procs) == 0 {
+		return ""
+	}
+	if watcher == "MEM" {
+		return fmt.Sprintf("%s %dMB", procs[0].Name, procs[0].Memory)
+	}
+	return fmt.Sprintf("%s %.0f%%", procs[0].Name, procs[0].CPU)
+}
+
+// recordTopProcesses logs and keeps the top processes when an alert is fired so the culprit can be found later.
+func (rt *Router) recordTopProcesses(watcher string) {
+	procs := rt.topProcesses(watcher)
+	if len(procs) == 0 {
+		return
+	}
+	tops := make([]string, 0, len(procs))
+	for _, p := range procs {
+		tops = append(tops, fmt.Sprintf("%s (pid: %d, user: %s, cpu: %.1f%%, mem: %dMB)", p.Name, p.PID, p.User, p.CPU, p.Memory))
+	}
+	logrus.Warnf("%s: alert fired, top processes: %s", watcher, strings.Join(tops, ", "))
+	rt.stats.update(func(ls *latestStats) {
+		if ls.Alerts == nil {
+			ls.Alerts = make(map[string]*processAlert)
+		}
+		ls.Alerts[strings.ToLower(watcher)] = &processAlert{Time: time.Now(), Processes: procs}
+	})
+}
+
+// escapeSerial replaces the characters reserved by serial protocol.
+func escapeSerial(s string) string {
+	return strings.NewReplacer("|", "_", "$", "_").Replace(s)
+}
+
+// topGPUProcessCmd returns the command to display the process using the most GPU memory.
+func topGPUProcessCmd(cards []gpu.Card) string {
+	var top *gpu.Process
+	for i := range cards {
+		// Processes of each card are sorted by memory usage
+		if len(cards[i].Processes) > 0 && (top == nil || cards[i].Processes[0].Mem > top.Mem) {
+			top = &cards[i].Processes[0]
+		}
+	}
+	if top == nil {
+		return "6|-|-$"
+	}
+	return fmt.Sprintf("6|%s|%d$", escapeSerial(top.Name), top.Mem)
+}
+
+// watcherAlert holds the alert status (ON/OFF) of a watcher and the status of each of its thresholds.
+type watcherAlert struct {
+	at     alertType
+	on     bool
+	parms  []bool
+	detail string // Sent along when the alert is turned on, e.g.: top process of CPU alert
+}
+
+func newWatcherAlert(at alertType, thresholds int) *watcherAlert {
+	return &watcherAlert{at: at, parms: make([]bool, thresholds)}
+}
+
+// check turns the alert on or off by the threshold flags.
+// Returns true if the alert has just been turned on.
+func (a *watcherAlert) check(sConn *serial.Port) bool {
+	on := a.on
+	alert(sConn, a.parms, &a.on, a.at, a.detail)
+	return !on && a.on
+}
+
+func (a *watcherAlert) reset() {
+	for i := range a.parms {
+		a.parms[i] = false
+	}
+}
+
+func alert(sConn *serial.Port, parms []bool, flag *bool, at alertType, detail string) {
 	for _, v := range parms {
 		if v { // Threshold reached
 			if !*flag { // Alert is not fired yet -> Turn on alert and update status
 				cmd := fmt.Sprintf("z|%d|1$", at)
+				if detail != "" {
+					cmd = fmt.Sprintf("z|%d|1|%s$", at, escapeSerial(detail))
+				}
 				if _, err := sConn.Write([]byte(cmd)); err != nil {
 					logrus.Errorf("alert: failed to write alert to Arduino: %s", cmd)
 					return
@@ -332,6 +942,15 @@ func (rt *Router) GetConfig(w http.ResponseWriter, r *http.Request) {
 	w.Write(b)
 }
 
+func (rt *Router) GetStats(w http.ResponseWriter, r *http.Request) {
+	b, err := rt.stats.marshal()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
+}
+
 func (rt *Router) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 	b, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -343,10 +962,19 @@ func (rt *Router) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if !ard.Stats.CPU.Enabled && !ard.Stats.Memory.Enabled && !ard.Stats.GPU.Enabled && !ard.Stats.Network.Enabled {
+	if !ard.Stats.CPU.Enabled && !ard.Stats.Memory.Enabled && !ard.Stats.GPU.Enabled && !ard.Stats.Network.Enabled &&
+		!ard.Stats.Disk.Enabled && !ard.Stats.Process.Enabled && !ard.Stats.PSI.Enabled && !ard.Stats.Kernel.Enabled &&
+		!ard.Stats.Cgroup.Enabled && !ard.Stats.Battery.Enabled && !ard.Stats.Sensor.Enabled &&
+		!ard.Stats.Power.Enabled && !ard.Stats.Custom.Enabled &&
+		!ard.Stats.Probe.Enabled && !ard.Stats.Logs.Enabled {
 		http.Error(w, "At least one system statistics must be enabled", http.StatusBadRequest)
 		return
 	}
+	if err = ard.Stats.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rt.keepFileOnlyStats(&ard.Stats)
 
 	tmpArd := rt.cfg.Arduino
 	rt.cfg.Arduino = ard
@@ -371,13 +999,26 @@ func (rt *Router) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, "Ok")
 }
 
+// keepFileOnlyStats keeps the stats settings which can only be changed in the config file.
+// They make the server run commands and plugins, write or read files and connect to other hosts,
+// so they must not be changeable by the config API which doesn't require login by default.
+func (rt *Router) keepFileOnlyStats(st *config.Stats) {
+	st.Custom.Commands = rt.cfg.Arduino.Stats.Custom.Commands
+	st.Custom.Plugins = rt.cfg.Arduino.Stats.Custom.Plugins
+	st.Custom.Prometheus = rt.cfg.Arduino.Stats.Custom.Prometheus
+	st.Power.EnergyFile = rt.cfg.Arduino.Stats.Power.EnergyFile
+	st.Cgroup.DockerSocket = rt.cfg.Arduino.Stats.Cgroup.DockerSocket
+	st.Logs.Patterns = rt.cfg.Arduino.Stats.Logs.Patterns
+	st.Probe.Targets = rt.cfg.Arduino.Stats.Probe.Targets
+}
+
 func (rt *Router) GetAdminConfig(w http.ResponseWriter, r *http.Request) {
 	b, err := json.Marshal(struct {
-		ForceLogin bool `json:"forceLogin"`
-		Username string `json:"username"`
+		ForceLogin bool   `json:"forceLogin"`
+		Username   string `json:"username"`
 	}{
 		ForceLogin: rt.cfg.Admin.ForceLogin,
-		Username: rt.cfg.Admin.Username,
+		Username:   rt.cfg.Admin.Username,
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
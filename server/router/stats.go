@@ -0,0 +1,79 @@
+package router
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lnquy/nights-watch/server/watcher/battery"
+	"github.com/lnquy/nights-watch/server/watcher/cgroup"
+	"github.com/lnquy/nights-watch/server/watcher/cpu"
+	"github.com/lnquy/nights-watch/server/watcher/custom"
+	"github.com/lnquy/nights-watch/server/watcher/disk"
+	"github.com/lnquy/nights-watch/server/watcher/gpu"
+	"github.com/lnquy/nights-watch/server/watcher/kernel"
+	"github.com/lnquy/nights-watch/server/watcher/logs"
+	"github.com/lnquy/nights-watch/server/watcher/mem"
+	"github.com/lnquy/nights-watch/server/watcher/net"
+	"github.com/lnquy/nights-watch/server/watcher/plugin"
+	"github.com/lnquy/nights-watch/server/watcher/power"
+	"github.com/lnquy/nights-watch/server/watcher/probe"
+	"github.com/lnquy/nights-watch/server/watcher/proc"
+	"github.com/lnquy/nights-watch/server/watcher/prometheus"
+	"github.com/lnquy/nights-watch/server/watcher/psi"
+	"github.com/lnquy/nights-watch/server/watcher/sensor"
+)
+
+// latestStats holds the latest statistics received from all watchers so it can be served via API.
+// Watchers keep modifying their own Stats objects so only copies are stored here.
+type latestStats struct {
+	mu      sync.RWMutex
+	CPU     *cpu.Stats     `json:"cpu,omitempty"`
+	Memory  *mem.Stats     `json:"memory,omitempty"`
+	GPU     *gpu.Stats     `json:"gpu,omitempty"`
+	Network *net.Stats     `json:"network,omitempty"`
+	Disk    *disk.Stats    `json:"disk,omitempty"`
+	Process *proc.Stats    `json:"process,omitempty"`
+	PSI     *psi.Stats     `json:"psi,omitempty"`
+	Kernel  *kernel.Stats  `json:"kernel,omitempty"`
+	Cgroup  *cgroup.Stats  `json:"cgroup,omitempty"`
+	Battery *battery.Stats `json:"battery,omitempty"`
+	Sensor  *sensor.Stats  `json:"sensor,omitempty"`
+	Power   *power.Stats   `json:"power,omitempty"`
+	Custom  *custom.Stats  `json:"custom,omitempty"`
+	Plugin  *plugin.Stats  `json:"plugin,omitempty"`
+	Probe   *probe.Stats   `json:"probe,omitempty"`
+	Logs    *logs.Stats    `json:"logs,omitempty"`
+
+	Prometheus *prometheus.Stats `json:"prometheus,omitempty"`
+
+	// Top processes when the CPU and MEM alerts were fired the last time, keyed by cpu and mem
+	Alerts map[string]*processAlert `json:"alerts,omitempty"`
+}
+
+// processAlert holds the top processes when an alert was fired.
+type processAlert struct {
+	Time      time.Time      `json:"time"`
+	Processes []proc.Process `json:"processes"`
+}
+
+func (ls *latestStats) update(fn func(ls *latestStats)) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	fn(ls)
+}
+
+func (ls *latestStats) reset() {
+	ls.update(func(ls *latestStats) {
+		ls.CPU, ls.Memory, ls.GPU, ls.Network, ls.Disk = nil, nil, nil, nil, nil
+		ls.Process, ls.PSI, ls.Kernel, ls.Cgroup, ls.Battery, ls.Sensor = nil, nil, nil, nil, nil, nil
+		ls.Power, ls.Custom, ls.Plugin, ls.Probe, ls.Logs = nil, nil, nil, nil, nil
+		ls.Prometheus, ls.Alerts = nil, nil
+	})
+}
+
+func (ls *latestStats) marshal() ([]byte, error) {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	return json.Marshal(ls)
+}
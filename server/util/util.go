@@ -1,13 +1,18 @@
 package util
 
 import (
+	"bufio"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"os"
 	"path"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/sirupsen/logrus"
 )
@@ -102,6 +107,65 @@ func GetAverage(arr []float64) float64 {
 	return sum / float64(len(arr))
 }
 
+// Truncate returns at most the first n bytes of s without cutting a multi-byte character.
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Delta returns the difference between 2 samples of a counter.
+// Some counters are still 32 bits (e.g.: network drivers, 32 bits kernels) which wrap around quickly,
+// otherwise a decreased counter means it has been reset (e.g.: device re-attached) so zero is returned.
+func Delta(last, cur uint64) uint64 {
+	if cur >= last {
+		return cur - last
+	}
+	if last <= math.MaxUint32 && last > math.MaxUint32/2 {
+		return cur + (math.MaxUint32 - last) + 1
+	}
+	return 0
+}
+
+// ReadUID returns the real UID of a process from /proc/[pid]/status, empty if not found.
+func ReadUID(fp string) string {
+	f, err := os.Open(fp)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[0] == "Uid:" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// ReadString returns the trimmed content of a file, e.g.: a sysfs attribute, empty if it's not readable.
+func ReadString(fp string) string {
+	b, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// ReadUint reads an unsigned integer from a file, e.g.: a sysfs attribute.
+func ReadUint(fp string) (uint64, error) {
+	b, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
 func DeleteCookie(w http.ResponseWriter, name string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:    name,
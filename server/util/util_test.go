@@ -0,0 +1,114 @@
+package util
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"", 5, ""},
+		{"short", 5, "short"},
+		{"longer line", 6, "longer"},
+		{"héllo", 2, "h"}, // é is 2 bytes
+		{"héllo", 3, "hé"},
+		{"日本語", 4, "日"},
+		{"日本語", 2, ""},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestDelta(t *testing.T) {
+	tests := []struct {
+		last, cur, want uint64
+	}{
+		{100, 150, 50},
+		{100, 100, 0},
+		{math.MaxUint32 - 9, 5, 15}, // 32 bits counter wrapped
+		{math.MaxUint32, 0, 1},
+		{math.MaxUint32 / 4, 5, 0},   // Reset, the counter was too low to wrap
+		{math.MaxUint32 + 100, 5, 0}, // 64 bits counter doesn't wrap, it's reset
+	}
+	for _, tt := range tests {
+		if got := Delta(tt.last, tt.cur); got != tt.want {
+			t.Errorf("Delta(%d, %d) = %d, want %d", tt.last, tt.cur, got, tt.want)
+		}
+	}
+}
+
+func TestReadUID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		status, want string
+	}{
+		{"Name:\tfirefox\nPid:\t3400\nUid:\t1000\t1001\t1001\t1001\nGid:\t1000\t1000\t1000\t1000\n", "1000"},
+		{"Name:\tkthreadd\nUid:\t0\t0\t0\t0\n", "0"},
+		{"Name:\tzombie\n", ""},
+	}
+	for _, tt := range tests {
+		fp := filepath.Join(dir, "status")
+		if err := ioutil.WriteFile(fp, []byte(tt.status), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := ReadUID(fp); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.status, got, tt.want)
+		}
+	}
+	if got := ReadUID(filepath.Join(dir, "missing")); got != "" {
+		t.Errorf("got %q for missing process, want empty", got)
+	}
+}
+
+func TestReadAttribute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sysfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		content string
+		s       string
+		v       uint64
+		err     bool
+	}{
+		{"1200\n", "1200", 1200, false},
+		{"  42  ", "42", 42, false},
+		{"max\n", "max", 0, true}, // E.g.: unlimited cgroup memory.max
+		{"-1\n", "-1", 0, true},
+		{"", "", 0, true},
+	}
+	for i, tt := range tests {
+		fp := filepath.Join(dir, "attr")
+		if err := ioutil.WriteFile(fp, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := ReadString(fp); got != tt.s {
+			t.Errorf("%d: got %q, want %q", i, got, tt.s)
+		}
+		v, err := ReadUint(fp)
+		if (err != nil) != tt.err || v != tt.v {
+			t.Errorf("%d: got %d (error: %v), want %d (error: %v)", i, v, err, tt.v, tt.err)
+		}
+	}
+	if got := ReadString(filepath.Join(dir, "missing")); got != "" {
+		t.Errorf("got %q for missing file, want empty", got)
+	}
+	if _, err := ReadUint(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("got error %v for missing file, want not exist", err)
+	}
+}
@@ -0,0 +1,121 @@
+package battery
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Battery status reported by power supply class
+const (
+	Charging    = "Charging"
+	Discharging = "Discharging"
+	Full        = "Full"
+	NotCharging = "Not charging"
+	Unknown     = "Unknown" // E.g.: idle battery which is neither charged nor full
+)
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	// Stats holds the combined state of all system batteries (e.g.: laptops with 2 batteries).
+	Stats struct {
+		Present       bool      `json:"present"` // False if there is no battery
+		ACOnline      bool      `json:"acOnline"`
+		Status        string    `json:"status"`
+		Percent       float64   `json:"percent"`
+		Rate          float64   `json:"rate"`          // Charge/discharge rate in Watts
+		TimeRemaining float64   `json:"timeRemaining"` // Minutes to empty when discharging or to full when charging
+		Health        float64   `json:"health"`        // Percent of full capacity over design capacity
+		Batteries     []Battery `json:"batteries"`
+	}
+
+	Battery struct {
+		Name       string  `json:"name"`
+		Status     string  `json:"status"`
+		Percent    float64 `json:"percent"`
+		Energy     float64 `json:"energy"`     // Wh
+		EnergyFull float64 `json:"energyFull"` // Wh
+		DesignFull float64 `json:"designFull"` // Wh
+		Rate       float64 `json:"rate"`       // Watts
+	}
+
+	watcher struct{}
+)
+
+func NewWatcher() Watcher {
+	return &watcher{}
+}
+
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	go func() {
+		logrus.Infof("watcher: BATTERY watcher started")
+		for {
+			select {
+			case <-ticker.C:
+				stats, err := getStats()
+				if err != nil {
+					logrus.Error(err)
+					continue
+				}
+				statsChan <- stats
+			case <-ctx.Done():
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: BATTERY watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
+
+// summarize combines the batteries into the summary fields.
+func (s *Stats) summarize() {
+	var energy, full, design, percent float64
+	statuses := make(map[string]bool)
+	for _, b := range s.Batteries {
+		energy, full, design = energy+b.Energy, full+b.EnergyFull, design+b.DesignFull
+		percent += b.Percent
+		s.Rate += b.Rate
+		statuses[b.Status] = true
+	}
+	s.Present = len(s.Batteries) > 0
+	if !s.Present {
+		return
+	}
+	s.Percent = percent / float64(len(s.Batteries))
+	if full > 0 {
+		s.Percent = energy / full * 100
+	}
+	if design > 0 {
+		s.Health = full / design * 100
+	}
+	// Batteries are drained one by one so any discharging battery means the system is on battery
+	switch {
+	case statuses[Discharging]:
+		s.Status = Discharging
+	case statuses[Charging]:
+		s.Status = Charging
+	case statuses[NotCharging]:
+		s.Status = NotCharging
+	case statuses[Full] && len(statuses) == 1:
+		s.Status = Full
+	default:
+		s.Status = Unknown
+	}
+	if s.Rate <= 0 {
+		return
+	}
+	switch s.Status {
+	case Discharging:
+		s.TimeRemaining = energy / s.Rate * 60
+	case Charging:
+		s.TimeRemaining = (full - energy) / s.Rate * 60
+	}
+}
@@ -0,0 +1,70 @@
+// +build linux
+
+package battery
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/lnquy/nights-watch/server/util"
+)
+
+var powerSupplyRoot = "/sys/class/power_supply"
+
+func getStats() (*Stats, error) {
+	dirs, err := filepath.Glob(filepath.Join(powerSupplyRoot, "*"))
+	if err != nil {
+		return nil, fmt.Errorf("battery: failed to list power supplies: %s", err)
+	}
+	stats := &Stats{Batteries: make([]Battery, 0)}
+	for _, dir := range dirs {
+		switch util.ReadString(filepath.Join(dir, "type")) {
+		case "Mains", "USB", "USB_C", "USB_PD":
+			if util.ReadString(filepath.Join(dir, "online")) == "1" {
+				stats.ACOnline = true
+			}
+		case "Battery":
+			// Skip peripheral batteries (e.g.: wireless mouse)
+			if util.ReadString(filepath.Join(dir, "scope")) == "Device" || util.ReadString(filepath.Join(dir, "present")) == "0" {
+				continue
+			}
+			stats.Batteries = append(stats.Batteries, readBattery(dir))
+		}
+	}
+	stats.summarize()
+	return stats, nil
+}
+
+// readBattery reads a battery which exposes either energy (µWh, µW) or charge (µAh, µA) values.
+func readBattery(dir string) Battery {
+	b := Battery{
+		Name:    filepath.Base(dir),
+		Status:  util.ReadString(filepath.Join(dir, "status")),
+		Percent: readFloat(dir, "capacity"),
+	}
+	if util.ReadString(filepath.Join(dir, "energy_full")) != "" {
+		b.Energy = readFloat(dir, "energy_now") / 1e6
+		b.EnergyFull = readFloat(dir, "energy_full") / 1e6
+		b.DesignFull = readFloat(dir, "energy_full_design") / 1e6
+		b.Rate = readFloat(dir, "power_now") / 1e6
+	} else {
+		voltage := readFloat(dir, "voltage_min_design")
+		if voltage == 0 {
+			voltage = readFloat(dir, "voltage_now")
+		}
+		b.Energy = readFloat(dir, "charge_now") * voltage / 1e12
+		b.EnergyFull = readFloat(dir, "charge_full") * voltage / 1e12
+		b.DesignFull = readFloat(dir, "charge_full_design") * voltage / 1e12
+		b.Rate = readFloat(dir, "current_now") * readFloat(dir, "voltage_now") / 1e12
+	}
+	if b.Rate < 0 { // Some drivers report negative current when discharging
+		b.Rate = -b.Rate
+	}
+	return b
+}
+
+func readFloat(dir, name string) float64 {
+	v, _ := strconv.ParseFloat(util.ReadString(filepath.Join(dir, name)), 64)
+	return v
+}
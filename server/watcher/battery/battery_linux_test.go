@@ -0,0 +1,58 @@
+// +build linux
+
+package battery
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+// Fixtures of power supply class in testdata: a laptop on battery with an energy (µWh) battery BAT0,
+// a charge (µAh) battery BAT1 and a wireless mouse battery, and a desktop without battery.
+func TestReadBattery(t *testing.T) {
+	tests := []struct {
+		name string
+		want Battery
+	}{
+		{"BAT0", Battery{Name: "BAT0", Status: Discharging, Percent: 60, Energy: 30, EnergyFull: 50, DesignFull: 60, Rate: 10}},
+		// Charge is converted by the design voltage, rate by the current voltage
+		{"BAT1", Battery{Name: "BAT1", Status: Unknown, Percent: 50, Energy: 22.2, EnergyFull: 44.4, DesignFull: 55.5, Rate: 6}},
+	}
+	for _, tt := range tests {
+		b := readBattery(filepath.Join("testdata", "laptop", tt.name))
+		if b.Name != tt.want.Name || b.Status != tt.want.Status || b.Percent != tt.want.Percent || !near(b.Energy, tt.want.Energy) ||
+			!near(b.EnergyFull, tt.want.EnergyFull) || !near(b.DesignFull, tt.want.DesignFull) || !near(b.Rate, tt.want.Rate) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, b, tt.want)
+		}
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	defer func(root string) { powerSupplyRoot = root }(powerSupplyRoot)
+
+	powerSupplyRoot = filepath.Join("testdata", "laptop")
+	s, err := getStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Present || s.ACOnline || len(s.Batteries) != 2 || s.Status != Discharging {
+		t.Fatalf("got %+v, want 2 system batteries discharging", s)
+	}
+	// 52.2Wh of 94.4Wh, 115.5Wh designed, discharging at 16W
+	if !near(s.Percent, 52.2/94.4*100) || !near(s.Health, 94.4/115.5*100) || !near(s.Rate, 16) || !near(s.TimeRemaining, 52.2/16*60) {
+		t.Errorf("got %+v", s)
+	}
+
+	powerSupplyRoot = filepath.Join("testdata", "desktop")
+	if s, err = getStats(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Present || !s.ACOnline || len(s.Batteries) != 0 || s.Status != "" {
+		t.Errorf("got %+v, want AC only", s)
+	}
+}
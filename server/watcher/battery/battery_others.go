@@ -0,0 +1,10 @@
+// +build !linux
+
+package battery
+
+import "fmt"
+
+// TODO: Windows/Darwin
+func getStats() (*Stats, error) {
+	return nil, fmt.Errorf("battery: battery watcher is only available on Linux")
+}
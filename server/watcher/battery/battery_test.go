@@ -0,0 +1,57 @@
+package battery
+
+import (
+	"testing"
+)
+
+func TestSummarizeStatus(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     string
+	}{
+		{[]string{Full}, Full},
+		{[]string{Full, Full}, Full},
+		{[]string{Unknown}, Unknown}, // Present but neither charging nor full
+		{[]string{Full, Unknown}, Unknown},
+		{[]string{Full, ""}, Unknown},
+		{[]string{NotCharging, Full}, NotCharging},
+		{[]string{Charging, Unknown}, Charging},
+		{[]string{Charging, Discharging}, Discharging},
+	}
+	for i, tt := range tests {
+		s := &Stats{}
+		for _, status := range tt.statuses {
+			s.Batteries = append(s.Batteries, Battery{Status: status, Percent: 50})
+		}
+		s.summarize()
+		if !s.Present || s.Status != tt.want {
+			t.Errorf("%d: got status %q (present: %v), want %q", i, s.Status, s.Present, tt.want)
+		}
+	}
+
+	s := &Stats{}
+	s.summarize()
+	if s.Present || s.Status != "" {
+		t.Errorf("got %+v without battery", s)
+	}
+}
+
+func TestSummarizeTimeRemaining(t *testing.T) {
+	tests := []struct {
+		battery Battery
+		percent float64
+		time    float64
+	}{
+		{Battery{Status: Discharging, Percent: 40, Energy: 20, EnergyFull: 40, DesignFull: 50, Rate: 10}, 50, 120},
+		{Battery{Status: Charging, Percent: 40, Energy: 20, EnergyFull: 40, DesignFull: 50, Rate: 40}, 50, 30},
+		{Battery{Status: Discharging, Percent: 40, Energy: 20, EnergyFull: 40}, 50, 0}, // Unknown rate
+		{Battery{Status: Full, Percent: 99}, 99, 0},                                    // No energy reported
+	}
+	for i, tt := range tests {
+		s := &Stats{Batteries: []Battery{tt.battery}}
+		s.summarize()
+		if s.Percent != tt.percent || s.TimeRemaining != tt.time {
+			t.Errorf("%d: got percent %v and %v minutes remaining, want %v and %v", i, s.Percent, s.TimeRemaining, tt.percent, tt.time)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package cgroup
+
+import (
+	"context"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	Stats struct {
+		Groups []Group `json:"groups"`
+	}
+
+	// Group holds the resource usage of a cgroup (v2).
+	Group struct {
+		Name        string  `json:"name"` // Container name or cgroup path
+		Path        string  `json:"path"` // Relative to cgroup root
+		ContainerID string  `json:"containerId,omitempty"`
+		CPU         float64 `json:"cpu"`      // Percent of one core, can be over 100 on multi-cores
+		MemUsage    uint64  `json:"memUsage"` // MB
+		MemLimit    uint64  `json:"memLimit"` // MB, zero if unlimited
+		MemLoad     float64 `json:"memLoad"`  // Percent of limit, zero if unlimited
+		IORead      uint64  `json:"ioRead"`   // KB/s
+		IOWrite     uint64  `json:"ioWrite"`  // KB/s
+		PIDs        uint64  `json:"pids"`
+	}
+
+	// containerLister lists the running containers, implemented by Docker Engine API client.
+	containerLister interface {
+		containers() ([]container, error)
+	}
+
+	container struct {
+		id   string
+		name string
+	}
+
+	watcher struct {
+		paths      []string
+		containers containerLister // Containers are not watched if nil
+	}
+)
+
+// NewWatcher returns a cgroup watcher which watches on the cgroup paths (relative to cgroup root,
+// e.g.: system.slice/nginx.service) and all running Docker containers if dockerSocket is not empty.
+func NewWatcher(paths []string, dockerSocket string) Watcher {
+	w := &watcher{paths: paths}
+	if dockerSocket != "" {
+		w.containers = newDockerClient(dockerSocket)
+	}
+	return w
+}
+
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	go func() {
+		logrus.Infof("watcher: CGROUP watcher started")
+		c := newCollector()
+		for {
+			select {
+			case <-ticker.C:
+				groups := w.groups()
+				stats, err := c.collect(groups)
+				if err != nil {
+					logrus.Error(err)
+					continue
+				}
+				sort.Slice(stats.Groups, func(i, j int) bool {
+					return stats.Groups[i].Name < stats.Groups[j].Name
+				})
+				statsChan <- stats
+			case <-ctx.Done():
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: CGROUP watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
+
+// Match checks if the group name (container name or cgroup path) or cgroup path matches the glob pattern.
+func (g *Group) Match(pattern string) bool {
+	if ok, _ := path.Match(pattern, g.Name); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, g.Path)
+	return ok
+}
+
+// groups returns the configured cgroups and the cgroups of running containers.
+func (w *watcher) groups() []Group {
+	groups := make([]Group, 0, len(w.paths))
+	for _, p := range w.paths {
+		groups = append(groups, Group{Name: p, Path: p})
+	}
+	if w.containers == nil {
+		return groups
+	}
+	cs, err := w.containers.containers()
+	if err != nil {
+		logrus.Debugf("cgroup: failed to list containers: %s", err)
+		return groups
+	}
+	for _, c := range cs {
+		path := containerPath(c.id)
+		if path == "" {
+			logrus.Debugf("cgroup: cgroup of container %s not found", c.name)
+			continue
+		}
+		groups = append(groups, Group{Name: c.name, Path: path, ContainerID: c.id})
+	}
+	return groups
+}
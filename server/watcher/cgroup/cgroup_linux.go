@@ -0,0 +1,143 @@
+// +build linux
+
+package cgroup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lnquy/nights-watch/server/util"
+)
+
+var cgroupRoot = "/sys/fs/cgroup" // cgroup v2 unified hierarchy
+
+type (
+	// Raw cumulative counters of a cgroup
+	counters struct {
+		cpuUsec    uint64
+		readBytes  uint64
+		writeBytes uint64
+	}
+
+	collector struct {
+		last     map[string]counters
+		lastTime time.Time
+	}
+)
+
+func newCollector() *collector {
+	return &collector{}
+}
+
+// collect reads the usages of the groups, CPU and I/O rates are zero on the first sample of a group.
+func (c *collector) collect(groups []Group) (*Stats, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup: cgroup v2 is not mounted at %s: %s", cgroupRoot, err)
+	}
+	now := time.Now()
+	elapsed := now.Sub(c.lastTime).Seconds()
+	cur := make(map[string]counters, len(groups))
+	stats := &Stats{Groups: make([]Group, 0, len(groups))}
+	for _, g := range groups {
+		dir := filepath.Join(cgroupRoot, filepath.Clean("/"+g.Path))
+		if _, err := os.Stat(dir); err != nil {
+			continue // Group has been removed (e.g.: container stopped)
+		}
+		var cnt counters
+		cnt.cpuUsec = readKey(filepath.Join(dir, "cpu.stat"), "usage_usec")
+		cnt.readBytes, cnt.writeBytes = readIOStat(filepath.Join(dir, "io.stat"))
+		cur[g.Path] = cnt
+
+		if usage, err := util.ReadUint(filepath.Join(dir, "memory.current")); err == nil {
+			g.MemUsage = usage / 1000000
+		}
+		// "max" means unlimited
+		if limit, err := util.ReadUint(filepath.Join(dir, "memory.max")); err == nil && limit > 0 {
+			g.MemLimit = limit / 1000000
+			g.MemLoad = float64(g.MemUsage) / float64(g.MemLimit) * 100
+		}
+		if pids, err := util.ReadUint(filepath.Join(dir, "pids.current")); err == nil {
+			g.PIDs = pids
+		}
+		if l, ok := c.last[g.Path]; ok && elapsed > 0 {
+			g.CPU = float64(util.Delta(l.cpuUsec, cnt.cpuUsec)) / 1000000 / elapsed * 100
+			g.IORead = uint64(float64(util.Delta(l.readBytes, cnt.readBytes)) / elapsed / 1000)
+			g.IOWrite = uint64(float64(util.Delta(l.writeBytes, cnt.writeBytes)) / elapsed / 1000)
+		}
+		stats.Groups = append(stats.Groups, g)
+	}
+	c.last, c.lastTime = cur, now
+	return stats, nil
+}
+
+// containerPath returns the cgroup path of a Docker container for both systemd and cgroupfs drivers.
+func containerPath(id string) string {
+	for _, p := range []string{
+		filepath.Join("system.slice", "docker-"+id+".scope"),
+		filepath.Join("docker", id),
+	} {
+		if _, err := os.Stat(filepath.Join(cgroupRoot, p)); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// readKey reads the value of a key in flat keyed files (e.g.: cpu.stat).
+func readKey(fp, key string) uint64 {
+	f, err := os.Open(fp)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			v, _ := strconv.ParseUint(fields[1], 10, 64)
+			return v
+		}
+	}
+	return 0
+}
+
+func readIOStat(fp string) (read, write uint64) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+	return parseIOStat(f)
+}
+
+// parseIOStat sums the bytes read and written of all devices in io.stat, e.g.:
+//
+//	8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+func parseIOStat(r io.Reader) (read, write uint64) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		for _, f := range strings.Fields(scanner.Text()) {
+			kv := strings.SplitN(f, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "rbytes":
+				read += v
+			case "wbytes":
+				write += v
+			}
+		}
+	}
+	return read, write
+}
@@ -0,0 +1,135 @@
+// +build linux
+
+package cgroup
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Fixture of cgroup v2 hierarchy in testdata: nginx service, a container run by systemd driver (A)
+// and a container run by cgroupfs driver (B).
+const (
+	testCgroupRoot = "testdata/cgroup"
+	testContainerA = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	testContainerB = "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+)
+
+type fakeLister struct {
+	cs  []container
+	err error
+}
+
+func (l *fakeLister) containers() ([]container, error) {
+	return l.cs, l.err
+}
+
+func TestGroups(t *testing.T) {
+	defer func(root string) { cgroupRoot = root }(cgroupRoot)
+	cgroupRoot = testCgroupRoot
+
+	w := &watcher{
+		paths: []string{"system.slice/nginx.service"},
+		containers: &fakeLister{cs: []container{
+			{id: testContainerA, name: "web"},
+			{id: testContainerB, name: "db"},
+			{id: "0000", name: "stopped"}, // cgroup is removed
+		}},
+	}
+	want := []Group{
+		{Name: "system.slice/nginx.service", Path: "system.slice/nginx.service"},
+		{Name: "web", Path: "system.slice/docker-" + testContainerA + ".scope", ContainerID: testContainerA},
+		{Name: "db", Path: "docker/" + testContainerB, ContainerID: testContainerB},
+	}
+	if got := w.groups(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Only configured paths are watched if containers can't be listed
+	w.containers = &fakeLister{err: errors.New("docker is not running")}
+	if got := w.groups(); !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("got %+v, want %+v", got, want[:1])
+	}
+}
+
+func TestCollect(t *testing.T) {
+	defer func(root string) { cgroupRoot = root }(cgroupRoot)
+	cgroupRoot = testCgroupRoot
+
+	groups := []Group{
+		{Name: "system.slice/nginx.service", Path: "system.slice/nginx.service"},
+		{Name: "web", Path: "system.slice/docker-" + testContainerA + ".scope", ContainerID: testContainerA},
+		{Name: "db", Path: "docker/" + testContainerB, ContainerID: testContainerB},
+		{Name: "gone", Path: "system.slice/gone.service"},
+		{Name: "escape", Path: "../../etc"}, // Paths are kept under cgroup root
+	}
+	c := newCollector()
+	s, err := c.collect(groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Group{
+		{Name: "system.slice/nginx.service", Path: "system.slice/nginx.service", MemUsage: 52, PIDs: 5},
+		{Name: "web", Path: "system.slice/docker-" + testContainerA + ".scope", ContainerID: testContainerA,
+			MemUsage: 268, MemLimit: 536, MemLoad: 50, PIDs: 12},
+		{Name: "db", Path: "docker/" + testContainerB, ContainerID: testContainerB, MemUsage: 1, PIDs: 1},
+	}
+	if !reflect.DeepEqual(s.Groups, want) {
+		t.Fatalf("got %+v, want %+v", s.Groups, want)
+	}
+
+	// nginx used 2s of CPU, read 2MB and wrote nothing in the last 2s
+	nginx := c.last["system.slice/nginx.service"]
+	nginx.cpuUsec -= 2000000
+	nginx.readBytes -= 2000000
+	c.last["system.slice/nginx.service"] = nginx
+	c.lastTime = time.Now().Add(-2 * time.Second)
+	if s, err = c.collect(groups); err != nil {
+		t.Fatal(err)
+	}
+	g := s.Groups[0]
+	if g.CPU < 95 || g.CPU > 100 || g.IORead < 950 || g.IORead > 1000 || g.IOWrite != 0 {
+		t.Errorf("unexpected nginx rates: %+v", g)
+	}
+	if g = s.Groups[1]; g.CPU != 0 || g.IORead != 0 {
+		t.Errorf("unexpected web rates: %+v", g)
+	}
+
+	cgroupRoot = "testdata/missing"
+	if _, err := c.collect(groups); err == nil {
+		t.Error("expected error without cgroup v2")
+	}
+}
+
+func TestParseIOStat(t *testing.T) {
+	tests := []struct {
+		content     string
+		read, write uint64
+	}{
+		{"8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0\n", 1459200, 314773504},
+		{"8:0 rbytes=100 wbytes=200 rios=1 wios=2\n259:0 rbytes=50 wbytes=25 rios=1 wios=1\n", 150, 225},
+		{"8:0 rbytes=abc wbytes=10\n253:0\n", 0, 10},
+		{"", 0, 0},
+	}
+	for _, tt := range tests {
+		if read, write := parseIOStat(strings.NewReader(tt.content)); read != tt.read || write != tt.write {
+			t.Errorf("%q: got %d/%d, want %d/%d", tt.content, read, write, tt.read, tt.write)
+		}
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	fp := testCgroupRoot + "/system.slice/nginx.service/cpu.stat"
+	if v := readKey(fp, "usage_usec"); v != 84211560 {
+		t.Errorf("got usage_usec %d, want 84211560", v)
+	}
+	if v := readKey(fp, "missing"); v != 0 {
+		t.Errorf("got missing key %d, want 0", v)
+	}
+	if v := readKey(testCgroupRoot+"/missing", "usage_usec"); v != 0 {
+		t.Errorf("got %d from missing file, want 0", v)
+	}
+}
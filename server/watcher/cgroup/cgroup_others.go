@@ -0,0 +1,19 @@
+// +build !linux
+
+package cgroup
+
+import "fmt"
+
+type collector struct{}
+
+func newCollector() *collector {
+	return &collector{}
+}
+
+func (c *collector) collect(groups []Group) (*Stats, error) {
+	return nil, fmt.Errorf("cgroup: cgroup watcher is only available on Linux")
+}
+
+func containerPath(id string) string {
+	return ""
+}
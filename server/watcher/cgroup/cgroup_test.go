@@ -0,0 +1,29 @@
+package cgroup
+
+import "testing"
+
+func TestGroupMatch(t *testing.T) {
+	web := Group{Name: "web", Path: "system.slice/docker-0123.scope", ContainerID: "0123"}
+	nginx := Group{Name: "system.slice/nginx.service", Path: "system.slice/nginx.service"}
+	tests := []struct {
+		pattern string
+		g       Group
+		want    bool
+	}{
+		{"web", web, true},
+		{"w*", web, true},
+		{"*", web, true},
+		{"system.slice/docker-*.scope", web, true}, // Container matched by cgroup path
+		{"db", web, false},
+		{"system.slice/nginx.service", nginx, true},
+		{"system.slice/*.service", nginx, true},
+		{"system.slice*", nginx, false}, // Glob doesn't cross path separator
+		{"*", nginx, false},
+		{"[", nginx, false}, // Invalid pattern
+	}
+	for _, tt := range tests {
+		if got := tt.g.Match(tt.pattern); got != tt.want {
+			t.Errorf("%q matches %s: got %v, want %v", tt.pattern, tt.g.Name, got, tt.want)
+		}
+	}
+}
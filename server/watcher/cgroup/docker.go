@@ -0,0 +1,64 @@
+package cgroup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Timeout of Docker Engine API requests
+var dockerTimeout = 5 * time.Second
+
+// dockerClient lists containers via Docker Engine API over its Unix socket.
+type dockerClient struct {
+	client *http.Client
+}
+
+func newDockerClient(socket string) *dockerClient {
+	return &dockerClient{
+		client: &http.Client{
+			Timeout: dockerTimeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+func (d *dockerClient) containers() ([]container, error) {
+	resp, err := d.client.Get("http://docker/containers/json") // Host is ignored by Unix socket
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("docker: unexpected response status: %s", resp.Status)
+	}
+
+	var list []struct {
+		ID    string   `json:"Id"`
+		Names []string `json:"Names"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("docker: failed to decode containers: %s", err)
+	}
+	cs := make([]container, 0, len(list))
+	for _, l := range list {
+		c := container{id: l.ID, name: l.ID}
+		if len(c.name) > 12 {
+			c.name = c.name[:12] // Short ID
+		}
+		if len(l.Names) > 0 {
+			c.name = strings.TrimPrefix(l.Names[0], "/")
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
@@ -0,0 +1,112 @@
+package cgroup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// dockerServer serves the handler on a Unix socket in dir.
+func dockerServer(t *testing.T, dir string, handler http.HandlerFunc) (*httptest.Server, string) {
+	socket := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(handler)
+	ts.Listener = l
+	ts.Start()
+	return ts, socket
+}
+
+func TestDockerContainers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var status int
+	var body string
+	ts, socket := dockerServer(t, dir, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	})
+	defer ts.Close()
+
+	tests := []struct {
+		status int
+		body   string
+		want   []container
+		err    bool
+	}{
+		{http.StatusOK, `[{"Id": "4c01db0b339c5c1b12a1b6a5d7a7e3bb3b1c3d1f6b5c0c4c9b9b7d1a3f2e1d0c", "Names": ["/web"]},
+			{"Id": "8dfafdbc3a40"}, {"Id": "abc", "Names": []}]`,
+			[]container{{"4c01db0b339c5c1b12a1b6a5d7a7e3bb3b1c3d1f6b5c0c4c9b9b7d1a3f2e1d0c", "web"}, {"8dfafdbc3a40", "8dfafdbc3a40"}, {"abc", "abc"}},
+			false},
+		{http.StatusOK, `[]`, []container{}, false},
+		{http.StatusInternalServerError, `{"message": "server error"}`, nil, true},
+		{http.StatusOK, `{"message": "not a list"}`, nil, true},
+		{http.StatusOK, `[{"Id": `, nil, true},
+	}
+	d := newDockerClient(socket)
+	for i, tt := range tests {
+		status, body = tt.status, tt.body
+		cs, err := d.containers()
+		if (err != nil) != tt.err {
+			t.Errorf("%d: got error %v, want error %v", i, err, tt.err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(cs, tt.want) {
+			t.Errorf("%d: got %+v, want %+v", i, cs, tt.want)
+		}
+	}
+}
+
+func TestDockerContainersUnavailable(t *testing.T) {
+	defer func(d time.Duration) { dockerTimeout = d }(dockerTimeout)
+	dockerTimeout = 100 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := newDockerClient(filepath.Join(dir, "missing.sock")).containers(); err == nil {
+		t.Error("expected error on missing socket")
+	}
+
+	// Socket accepts connections but never responds
+	socket := filepath.Join(dir, "hung.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+	start := time.Now()
+	if _, err := newDockerClient(socket).containers(); err == nil {
+		t.Error("expected error on no response")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("got no response error after %s, want after timeout", d)
+	}
+}
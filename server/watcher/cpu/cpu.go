@@ -11,8 +11,19 @@ type (
 	}
 
 	Stats struct {
-		Load float64
-		Temp float64
+		Load     float64  `json:"load"`
+		Temp     float64  `json:"temp"`
+		Throttle Throttle `json:"throttle"` // Linux only
+	}
+
+	// Throttle holds the thermal/power limit throttling counters of all cores and packages since boot.
+	Throttle struct {
+		Throttled       bool     `json:"throttled"` // CPU has been throttled since the last sample
+		Reasons         []string `json:"reasons"`   // thermal, power_limit or frequency (below base frequency under load)
+		ThermalCount    uint64   `json:"thermalCount"`
+		PowerLimitCount uint64   `json:"powerLimitCount"`
+		Frequency       float64  `json:"frequency"`     // Average current frequency (MHz)
+		BaseFrequency   float64  `json:"baseFrequency"` // MHz, zero if unknown
 	}
 
 	watcher struct{}
@@ -20,6 +20,7 @@ func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *
 	ticker := time.NewTicker(interval)
 	statsChan := make(chan *Stats, 10)
 	stats := &Stats{}
+	throttle := &throttleDetector{}
 	go func() {
 		logrus.Infof("watcher: CPU watcher started")
 		for {
@@ -31,6 +32,7 @@ func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *
 					continue
 				}
 				stats.Load = util.GetAverage(percs)
+				stats.Throttle = throttle.detect(stats.Load)
 				temps, err := pshost.SensorsTemperatures()
 				if err != nil {
 					statsChan <- stats
@@ -0,0 +1,84 @@
+// +build linux
+
+package cpu
+
+import (
+	"path/filepath"
+
+	"github.com/lnquy/nights-watch/server/util"
+)
+
+var cpuSysRoot = "/sys/devices/system/cpu"
+
+// CPU frequency is only compared to the base frequency when load is at least this percent,
+// an idle CPU always runs below its base frequency.
+const throttleLoad = 80
+
+// throttleDetector detects CPU throttling from the changes of thermal_throttle counters since the last sample
+// and the current frequency of a loaded CPU.
+type throttleDetector struct {
+	thermal, powerLimit uint64
+	started             bool
+}
+
+func (d *throttleDetector) detect(load float64) Throttle {
+	t := Throttle{Reasons: make([]string, 0)}
+	cpus, _ := filepath.Glob(filepath.Join(cpuSysRoot, "cpu[0-9]*"))
+	cores, packages := make(map[string]bool), make(map[string]bool)
+	var freq, base float64
+	freqs := 0
+	for _, dir := range cpus {
+		tt := filepath.Join(dir, "thermal_throttle")
+		pkg := util.ReadString(filepath.Join(dir, "topology", "physical_package_id"))
+		// Core counters are the same on all SMT siblings of a core
+		core := util.ReadString(filepath.Join(dir, "topology", "core_id"))
+		if core == "" {
+			core = dir
+		}
+		if core = pkg + "/" + core; !cores[core] {
+			cores[core] = true
+			thermal, _ := util.ReadUint(filepath.Join(tt, "core_throttle_count"))
+			powerLimit, _ := util.ReadUint(filepath.Join(tt, "core_power_limit_count"))
+			t.ThermalCount += thermal
+			t.PowerLimitCount += powerLimit
+		}
+		// Package counters are the same on all CPUs of a package
+		if !packages[pkg] {
+			packages[pkg] = true
+			thermal, _ := util.ReadUint(filepath.Join(tt, "package_throttle_count"))
+			powerLimit, _ := util.ReadUint(filepath.Join(tt, "package_power_limit_count"))
+			t.ThermalCount += thermal
+			t.PowerLimitCount += powerLimit
+		}
+
+		cur, _ := util.ReadUint(filepath.Join(dir, "cpufreq", "scaling_cur_freq"))
+		if cur == 0 {
+			continue
+		}
+		freq += float64(cur)
+		freqs++
+		// Only intel_pstate reports base frequency
+		if b, _ := util.ReadUint(filepath.Join(dir, "cpufreq", "base_frequency")); float64(b) > base {
+			base = float64(b)
+		}
+	}
+	if freqs > 0 {
+		t.Frequency = freq / float64(freqs) / 1000 // kHz to MHz
+	}
+	t.BaseFrequency = base / 1000
+
+	if d.started {
+		if t.ThermalCount > d.thermal {
+			t.Reasons = append(t.Reasons, "thermal")
+		}
+		if t.PowerLimitCount > d.powerLimit {
+			t.Reasons = append(t.Reasons, "power_limit")
+		}
+	}
+	if load >= throttleLoad && t.BaseFrequency > 0 && t.Frequency > 0 && t.Frequency < t.BaseFrequency {
+		t.Reasons = append(t.Reasons, "frequency")
+	}
+	t.Throttled = len(t.Reasons) > 0
+	d.thermal, d.powerLimit, d.started = t.ThermalCount, t.PowerLimitCount, true
+	return t
+}
@@ -0,0 +1,92 @@
+// +build linux
+
+package cpu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Fixtures of /sys/devices/system/cpu in testdata/cpu: package 0 has one core with two SMT siblings (cpu0, cpu1),
+// package 1 has two cores (cpu2, cpu3). Counters are read once per core and once per package.
+const testCPURoot = "testdata/cpu"
+
+// copyCPU copies the cpu fixtures to a temporary directory which can be changed between samples.
+func copyCPU(t *testing.T) string {
+	root, err := ioutil.TempDir("", "cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = filepath.Walk(testCPURoot, func(fp string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(testCPURoot, fp)
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(root, rel), 0755)
+		}
+		b, err := ioutil.ReadFile(fp)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(root, rel), b, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestThrottleDetect(t *testing.T) {
+	defer func(root string) { cpuSysRoot = root }(cpuSysRoot)
+	cpuSysRoot = copyCPU(t)
+	defer os.RemoveAll(cpuSysRoot)
+
+	write := func(value string, names ...string) {
+		for _, name := range names {
+			if err := ioutil.WriteFile(filepath.Join(cpuSysRoot, name), []byte(value+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	d := &throttleDetector{}
+	steps := []struct {
+		change              func()
+		load                float64
+		thermal, powerLimit uint64
+		freq                float64
+		want                []string
+	}{
+		{func() {}, 90, 130, 23, 2500, []string{"frequency"}}, // Counters are only compared from the second sample
+		{func() {}, 10, 130, 23, 2500, []string{}},            // Idle CPU below base frequency
+		{func() {
+			write("6", "cpu0/thermal_throttle/core_throttle_count", "cpu1/thermal_throttle/core_throttle_count")
+		}, 10, 131, 23, 2500, []string{"thermal"}},
+		{func() {
+			write("12", "cpu2/thermal_throttle/package_power_limit_count", "cpu3/thermal_throttle/package_power_limit_count")
+		}, 10, 131, 25, 2500, []string{"power_limit"}},
+		{func() {
+			write("3000000", "cpu0/cpufreq/scaling_cur_freq", "cpu1/cpufreq/scaling_cur_freq")
+		}, 90, 131, 25, 3000, []string{}},
+		{func() {
+			write("0", "cpu0/thermal_throttle/core_throttle_count", "cpu1/thermal_throttle/core_throttle_count")
+			write("1000000", "cpu2/cpufreq/scaling_cur_freq", "cpu3/cpufreq/scaling_cur_freq")
+		}, 85, 125, 25, 2000, []string{"frequency"}}, // Counter reset isn't throttling
+	}
+	for i, step := range steps {
+		step.change()
+		got := d.detect(step.load)
+		if got.ThermalCount != step.thermal || got.PowerLimitCount != step.powerLimit {
+			t.Errorf("%d: got thermal %d, power limit %d, want %d, %d", i, got.ThermalCount, got.PowerLimitCount, step.thermal, step.powerLimit)
+		}
+		if got.Frequency != step.freq || got.BaseFrequency != 3000 {
+			t.Errorf("%d: got frequency %v of base %v, want %v of 3000", i, got.Frequency, got.BaseFrequency, step.freq)
+		}
+		if !reflect.DeepEqual(got.Reasons, step.want) || got.Throttled != (len(step.want) > 0) {
+			t.Errorf("%d: got reasons %v (throttled: %v), want %v", i, got.Reasons, got.Throttled, step.want)
+		}
+	}
+}
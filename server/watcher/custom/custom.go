@@ -0,0 +1,149 @@
+package custom
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Timeout of commands which don't define their own timeout
+var DefaultTimeout = 10 * time.Second
+
+// Commands which print more than this to stdout are failed
+const maxOutputSize = 1024 * 1024
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	// Command is a user-defined command or script which prints metrics to stdout, run by the system shell.
+	Command struct {
+		Name    string
+		Command string
+		Timeout time.Duration
+	}
+
+	Stats struct {
+		Metrics []Metric          `json:"metrics"`
+		Errors  map[string]string `json:"errors"` // Command name to the error of the last run
+	}
+
+	// Metric is a numeric value parsed from the command output. Its name is the command name for plain number
+	// outputs, otherwise the key is appended to the command name, e.g.: queue.length
+	Metric struct {
+		Name    string  `json:"name"`
+		Command string  `json:"command"`
+		Value   float64 `json:"value"`
+	}
+
+	watcher struct {
+		commands []Command
+	}
+)
+
+func NewWatcher(commands []Command) Watcher {
+	return &watcher{commands: commands}
+}
+
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	go func() {
+		logrus.Infof("watcher: CUSTOM watcher started")
+		for {
+			select {
+			case <-ticker.C:
+				statsChan <- w.run(ctx)
+			case <-ctx.Done():
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: CUSTOM watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
+
+// run runs all commands concurrently and waits until they exited or timed out.
+func (w *watcher) run(ctx context.Context) *Stats {
+	results := make([][]Metric, len(w.commands))
+	errs := make([]error, len(w.commands))
+	var wg sync.WaitGroup
+	for i := range w.commands {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = runCommand(ctx, w.commands[i])
+		}(i)
+	}
+	wg.Wait()
+
+	stats := &Stats{
+		Metrics: make([]Metric, 0),
+		Errors:  make(map[string]string),
+	}
+	for i, c := range w.commands {
+		if errs[i] != nil {
+			logrus.Errorf("custom: %s: %s", c.Name, errs[i])
+			stats.Errors[c.Name] = errs[i].Error()
+			continue
+		}
+		stats.Metrics = append(stats.Metrics, results[i]...)
+	}
+	return stats
+}
+
+func runCommand(ctx context.Context, c Command) ([]Metric, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	out, err := runShell(ctx, c.Command)
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("command timed out after %s", timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("command failed: %s", err)
+	}
+	metrics, err := parseOutput(c.Name, out)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Name < metrics[j].Name
+	})
+	return metrics, nil
+}
+
+// outputBuffer keeps at most maxOutputSize bytes of a command output,
+// the rest is discarded so the command doesn't block on writing it.
+type outputBuffer struct {
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	if n := maxOutputSize - b.buf.Len(); len(p) > n {
+		b.overflow = true
+		b.buf.Write(p[:n])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// bytes returns the output, or an error if it's larger than maxOutputSize.
+func (b *outputBuffer) bytes() ([]byte, error) {
+	if b.overflow {
+		return nil, fmt.Errorf("output is larger than %d bytes", maxOutputSize)
+	}
+	return b.buf.Bytes(), nil
+}
@@ -0,0 +1,95 @@
+package custom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lnquy/nights-watch/server/util"
+)
+
+// parseOutput parses the metrics from a command output, which can be:
+//
+//	A plain number:  42
+//	Key=value lines: length=42
+//	                 oldest=12.5
+//	A JSON object:   {"length": 42, "workers": {"busy": 3}}
+//
+// Nested JSON keys are joined by dots (e.g.: workers.busy), booleans are 0 or 1 and other values are ignored.
+// NaN and infinite values fail the output since they can't be reported in JSON.
+func parseOutput(name string, out []byte) ([]Metric, error) {
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no output")
+	}
+	metrics := make([]Metric, 0)
+	add := func(key string, v float64) {
+		if key != "" {
+			key = name + "." + key
+		} else {
+			key = name
+		}
+		metrics = append(metrics, Metric{Name: key, Command: name, Value: v})
+	}
+
+	if out[0] == '{' {
+		var obj map[string]interface{}
+		if err := json.Unmarshal(out, &obj); err != nil {
+			return nil, fmt.Errorf("invalid JSON output: %s", err)
+		}
+		flattenJSON("", obj, add)
+	} else if v, err := strconv.ParseFloat(string(out), 64); err == nil {
+		if !isFinite(v) {
+			return nil, fmt.Errorf("output is not a finite number: %q", out)
+		}
+		add("", v)
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(out))
+		for scanner.Scan() {
+			kv := strings.SplitN(scanner.Text(), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+			if err != nil {
+				continue
+			}
+			if !isFinite(v) {
+				return nil, fmt.Errorf("value of %s is not a finite number: %q", strings.TrimSpace(kv[0]), kv[1])
+			}
+			add(strings.TrimSpace(kv[0]), v)
+		}
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("no numeric value found in output: %q", util.Truncate(string(out), 100))
+	}
+	return metrics, nil
+}
+
+func flattenJSON(prefix string, obj map[string]interface{}, add func(key string, v float64)) {
+	for k, v := range obj {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case float64:
+			add(k, v)
+		case bool:
+			if v {
+				add(k, 1)
+			} else {
+				add(k, 0)
+			}
+		case map[string]interface{}:
+			flattenJSON(k, v, add)
+		}
+	}
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
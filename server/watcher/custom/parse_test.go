@@ -0,0 +1,89 @@
+package custom
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		out  string
+		want map[string]float64
+	}{
+		{"42\n", map[string]float64{"queue": 42}},
+		{"  -1.5e3 ", map[string]float64{"queue": -1500}},
+		{"length=42\noldest = 12.5\n", map[string]float64{"queue.length": 42, "queue.oldest": 12.5}},
+		{"length=42\nstatus=ok\nno value\n=7\n", map[string]float64{"queue.length": 42, "queue": 7}},
+		{"url=http://host/?a=1\nlength=3", map[string]float64{"queue.length": 3}},
+		{`{"length": 42, "workers": {"busy": 3, "idle": 1}, "ok": true, "down": false, "name": "jobs", "ids": [1, 2]}`,
+			map[string]float64{"queue.length": 42, "queue.workers.busy": 3, "queue.workers.idle": 1, "queue.ok": 1, "queue.down": 0}},
+	}
+	for _, tt := range tests {
+		metrics, err := parseOutput("queue", []byte(tt.out))
+		if err != nil {
+			t.Errorf("parseOutput(%q): %s", tt.out, err)
+			continue
+		}
+		got := make(map[string]float64)
+		for _, m := range metrics {
+			if m.Command != "queue" {
+				t.Errorf("parseOutput(%q): got command %s of %s, want queue", tt.out, m.Command, m.Name)
+			}
+			got[m.Name] = m.Value
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseOutput(%q) = %v, want %v", tt.out, got, tt.want)
+		}
+	}
+
+	invalid := []string{
+		"",
+		" \n",
+		"ok",
+		"status=ok",
+		`{"length": 42`,
+		`{"name": "jobs"}`,
+		"nan",
+		"+Inf",
+		"length=42\ntemp=inf",
+		"length=NaN",
+		"1e999", // Out of range
+	}
+	for _, out := range invalid {
+		if metrics, err := parseOutput("queue", []byte(out)); err == nil {
+			t.Errorf("parseOutput(%q) = %+v, want error", out, metrics)
+		}
+	}
+}
+
+func TestParseOutputErrorTruncated(t *testing.T) {
+	// Multi-byte characters aren't cut in the error message
+	_, err := parseOutput("queue", []byte(strings.Repeat("é", 100)))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if msg := err.Error(); strings.Contains(msg, `\x`) || !strings.Contains(msg, strings.Repeat("é", 50)) {
+		t.Errorf("got %s, want 50 whole characters", msg)
+	}
+}
+
+func TestOutputBuffer(t *testing.T) {
+	var b outputBuffer
+	b.Write([]byte("42\n"))
+	if out, err := b.bytes(); err != nil || string(out) != "42\n" {
+		t.Errorf("got %q (error: %v), want 42", out, err)
+	}
+	chunk := []byte(strings.Repeat("a", 4096))
+	for i := 0; i < maxOutputSize/len(chunk)+1; i++ {
+		if n, err := b.Write(chunk); n != len(chunk) || err != nil {
+			t.Fatalf("%d: got %d (error: %v), want output is drained", i, n, err)
+		}
+	}
+	if b.buf.Len() != maxOutputSize {
+		t.Errorf("got %d bytes buffered, want %d", b.buf.Len(), maxOutputSize)
+	}
+	if _, err := b.bytes(); err == nil {
+		t.Error("expected error on too large output")
+	}
+}
@@ -0,0 +1,37 @@
+// +build !windows
+
+package custom
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// runShell runs the command by sh and returns its stdout. The command runs in its own process group
+// so its children are also killed when ctx is done, otherwise they keep stdout open.
+// Output larger than maxOutputSize fails the command.
+func runShell(ctx context.Context, command string) ([]byte, error) {
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	var out outputBuffer
+	cmd.Stdout = &out
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, err
+		}
+		return out.bytes()
+	case <-ctx.Done():
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return nil, ctx.Err()
+	}
+}
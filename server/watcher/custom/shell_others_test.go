@@ -0,0 +1,42 @@
+// +build !windows
+
+package custom
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCommand(t *testing.T) {
+	metrics, err := runCommand(context.Background(), Command{Name: "queue", Command: "echo length=2; echo busy=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Metric{{Name: "queue.busy", Command: "queue", Value: 1}, {Name: "queue.length", Command: "queue", Value: 2}}
+	if !reflect.DeepEqual(metrics, want) {
+		t.Errorf("got %+v, want %+v", metrics, want)
+	}
+
+	tests := []struct {
+		command string
+		err     string
+	}{
+		{"exit 3", "command failed"},
+		{"echo nan", "not a finite number"},
+		{"head -c 2000000 /dev/zero | tr '\\0' 1", "larger than"},
+		{"sleep 10 & echo 1; wait", "timed out"}, // Children are killed with the shell
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		_, err := runCommand(context.Background(), Command{Name: "queue", Command: tt.command, Timeout: 200 * time.Millisecond})
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %v, want %s error", tt.command, err, tt.err)
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("%s: took %s", tt.command, d)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// +build windows
+
+package custom
+
+import (
+	"context"
+	"os/exec"
+)
+
+// runShell runs the command by cmd.exe and returns its stdout, output larger than maxOutputSize fails the command.
+func runShell(ctx context.Context, command string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "cmd", "/C", command)
+	var out outputBuffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return out.bytes()
+}
@@ -0,0 +1,128 @@
+package disk
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	// Stats holds the disk statistics of all watched mount points and their backing devices.
+	// Load, Free, InodesLoad and Latency are summaries used for display and alert.
+	Stats struct {
+		Load       float64 `json:"load"`       // Highest used percent among all mount points
+		Free       uint64  `json:"free"`       // Free space (MB) of the fullest mount point
+		InodesLoad float64 `json:"inodesLoad"` // Highest used inodes percent among all mount points
+		Latency    float64 `json:"latency"`    // Highest average I/O latency (ms) among all devices
+
+		Mounts  []MountStats  `json:"mounts"`
+		Devices []DeviceStats `json:"devices"`
+	}
+
+	// MountStats holds the space usage of a mount point. Sizes are in MB.
+	MountStats struct {
+		Mountpoint        string  `json:"mountpoint"`
+		Device            string  `json:"device"`
+		Fstype            string  `json:"fstype"`
+		Total             uint64  `json:"total"`
+		Used              uint64  `json:"used"`
+		Free              uint64  `json:"free"`
+		UsedPercent       float64 `json:"usedPercent"`
+		Inodes            uint64  `json:"inodes"`
+		InodesUsed        uint64  `json:"inodesUsed"`
+		InodesUsedPercent float64 `json:"inodesUsedPercent"`
+	}
+
+	// DeviceStats holds the I/O statistics of a block device.
+	DeviceStats struct {
+		Name       string  `json:"name"`
+		ReadSpeed  uint64  `json:"readSpeed"`  // KB/s
+		WriteSpeed uint64  `json:"writeSpeed"` // KB/s
+		ReadIOPS   float64 `json:"readIOPS"`   // Read operations per second
+		WriteIOPS  float64 `json:"writeIOPS"`  // Write operations per second
+		Latency    float64 `json:"latency"`    // Average time (ms) spent per I/O operation
+	}
+
+	watcher struct {
+		include []string
+		exclude []string
+	}
+)
+
+// NewWatcher returns a disk watcher which only watches on mount points matched the include
+// glob patterns (all mount points if empty) and not matched any of the exclude patterns.
+func NewWatcher(include, exclude []string) Watcher {
+	return &watcher{
+		include: include,
+		exclude: exclude,
+	}
+}
+
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	go func() {
+		logrus.Infof("watcher: DISK watcher started")
+		c := newCollector(w)
+		for {
+			select {
+			case <-ticker.C:
+				stats, err := c.collect()
+				if err != nil {
+					logrus.Error(err)
+					continue
+				}
+				statsChan <- stats
+			case <-ctx.Done():
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: DISK watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
+
+// isWatched checks if the mount point should be watched or not.
+func (w *watcher) isWatched(mountpoint string) bool {
+	for _, p := range w.exclude {
+		if ok, _ := filepath.Match(p, mountpoint); ok {
+			return false
+		}
+	}
+	if len(w.include) == 0 {
+		return true
+	}
+	for _, p := range w.include {
+		if ok, _ := filepath.Match(p, mountpoint); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// summarize fills the summary fields from the mount point and device statistics.
+func (s *Stats) summarize() {
+	s.Load, s.Free, s.InodesLoad, s.Latency = 0, 0, 0, 0
+	for i, m := range s.Mounts {
+		if i == 0 || m.UsedPercent > s.Load {
+			s.Load = m.UsedPercent
+			s.Free = m.Free
+		}
+		if m.InodesUsedPercent > s.InodesLoad {
+			s.InodesLoad = m.InodesUsedPercent
+		}
+	}
+	for _, d := range s.Devices {
+		if d.Latency > s.Latency {
+			s.Latency = d.Latency
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// +build linux
+
+package disk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lnquy/nights-watch/server/util"
+)
+
+const sectorSize = 512 // /proc/diskstats always counts in 512 bytes sectors
+
+var (
+	mountsFile    = "/proc/self/mounts"
+	diskstatsFile = "/proc/diskstats"
+
+	// Pseudo filesystems which have no backing storage, skipped unless being included explicitly.
+	pseudoFs = map[string]bool{
+		"autofs": true, "binfmt_misc": true, "bpf": true, "cgroup": true, "cgroup2": true,
+		"configfs": true, "debugfs": true, "devpts": true, "devtmpfs": true, "fusectl": true,
+		"hugetlbfs": true, "mqueue": true, "nsfs": true, "proc": true,
+		"pstore": true, "ramfs": true, "securityfs": true, "squashfs": true, "sysfs": true,
+		"tmpfs": true, "tracefs": true, "efivarfs": true, "rpc_pipefs": true,
+	}
+)
+
+type (
+	mount struct {
+		device     string
+		mountpoint string
+		fstype     string
+	}
+
+	// Raw cumulative counters of a device read from /proc/diskstats
+	ioCounters struct {
+		reads     uint64
+		readSecs  uint64
+		readTime  uint64 // ms
+		writes    uint64
+		writeSecs uint64
+		writeTime uint64 // ms
+	}
+
+	collector struct {
+		w        *watcher
+		last     map[string]ioCounters
+		lastTime time.Time
+	}
+)
+
+func newCollector(w *watcher) *collector {
+	return &collector{w: w}
+}
+
+func (c *collector) collect() (*Stats, error) {
+	f, err := os.Open(mountsFile)
+	if err != nil {
+		return nil, err
+	}
+	mounts, err := parseMounts(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{}
+	devices := make(map[string]bool)
+	for _, m := range mounts {
+		if !c.w.isWatched(m.mountpoint) || (pseudoFs[m.fstype] && len(c.w.include) == 0) {
+			continue
+		}
+		ms, err := getMountStats(m)
+		if err != nil || ms.Total == 0 {
+			continue
+		}
+		stats.Mounts = append(stats.Mounts, ms)
+		if name := deviceName(m.device); name != "" {
+			devices[name] = true
+		}
+	}
+
+	f, err = os.Open(diskstatsFile)
+	if err != nil {
+		return nil, err
+	}
+	counters, err := parseDiskstats(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if c.last != nil { // First sample is only used to initialize the counters
+		elapsed := now.Sub(c.lastTime).Seconds()
+		for name := range devices {
+			cur, ok := counters[name]
+			last, lok := c.last[name]
+			if !ok || !lok || elapsed <= 0 {
+				continue
+			}
+			stats.Devices = append(stats.Devices, getDeviceStats(name, last, cur, elapsed))
+		}
+	}
+	c.last, c.lastTime = counters, now
+
+	stats.summarize()
+	return stats, nil
+}
+
+func getMountStats(m mount) (MountStats, error) {
+	fs := syscall.Statfs_t{}
+	if err := syscall.Statfs(m.mountpoint, &fs); err != nil {
+		return MountStats{}, err
+	}
+	bsize := uint64(fs.Bsize)
+	ms := MountStats{
+		Mountpoint: m.mountpoint,
+		Device:     m.device,
+		Fstype:     m.fstype,
+		Total:      fs.Blocks * bsize / 1000000,
+		Used:       (fs.Blocks - fs.Bfree) * bsize / 1000000,
+		Free:       fs.Bavail * bsize / 1000000,
+		Inodes:     fs.Files,
+		InodesUsed: fs.Files - fs.Ffree,
+	}
+	// Same as df, reserved blocks are not counted as available to normal users
+	if used, avail := fs.Blocks-fs.Bfree, fs.Bavail; used+avail > 0 {
+		ms.UsedPercent = float64(used) / float64(used+avail) * 100
+	}
+	if ms.Inodes > 0 {
+		ms.InodesUsedPercent = float64(ms.InodesUsed) / float64(ms.Inodes) * 100
+	}
+	return ms, nil
+}
+
+func getDeviceStats(name string, last, cur ioCounters, elapsed float64) DeviceStats {
+	ds := DeviceStats{Name: name}
+	reads, writes := util.Delta(last.reads, cur.reads), util.Delta(last.writes, cur.writes)
+	ds.ReadSpeed = uint64(float64(util.Delta(last.readSecs, cur.readSecs)*sectorSize) / elapsed / 1000)
+	ds.WriteSpeed = uint64(float64(util.Delta(last.writeSecs, cur.writeSecs)*sectorSize) / elapsed / 1000)
+	ds.ReadIOPS = float64(reads) / elapsed
+	ds.WriteIOPS = float64(writes) / elapsed
+	if ios := reads + writes; ios > 0 {
+		ds.Latency = float64(util.Delta(last.readTime, cur.readTime)+util.Delta(last.writeTime, cur.writeTime)) / float64(ios)
+	}
+	return ds
+}
+
+// deviceName returns the kernel name of block device (e.g.: /dev/mapper/root -> dm-0).
+func deviceName(device string) string {
+	if !strings.HasPrefix(device, "/dev/") {
+		return ""
+	}
+	if p, err := filepath.EvalSymlinks(device); err == nil {
+		device = p
+	}
+	return filepath.Base(device)
+}
+
+func parseMounts(r io.Reader) ([]mount, error) {
+	mounts := make([]mount, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		mounts = append(mounts, mount{
+			device:     unescape(fields[0]),
+			mountpoint: unescape(fields[1]),
+			fstype:     fields[2],
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+func parseDiskstats(r io.Reader) (map[string]ioCounters, error) {
+	counters := make(map[string]ioCounters)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 14 {
+			continue
+		}
+		vals := make([]uint64, 11)
+		for i := range vals {
+			v, err := strconv.ParseUint(fields[i+3], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("disk: invalid diskstats line %q: %s", scanner.Text(), err)
+			}
+			vals[i] = v
+		}
+		counters[fields[2]] = ioCounters{
+			reads:     vals[0],
+			readSecs:  vals[2],
+			readTime:  vals[3],
+			writes:    vals[4],
+			writeSecs: vals[6],
+			writeTime: vals[7],
+		}
+	}
+	return counters, scanner.Err()
+}
+
+// unescape decodes the octal escaped characters (e.g.: \040 for space) in /proc/self/mounts.
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,112 @@
+// +build linux
+
+package disk
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDiskstats(t *testing.T) {
+	f, err := os.Open("testdata/diskstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	counters, err := parseDiskstats(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]ioCounters{
+		// Kernel 5.5+ with discard and flush fields
+		"nvme0n1": {reads: 1248913, readSecs: 93281742, readTime: 361275, writes: 2193817, writeSecs: 187219834, writeTime: 2947812},
+		"dm-0":    {reads: 1658201, readSecs: 93258290, readTime: 531842, writes: 4067839, writeSecs: 187219832, writeTime: 14202891},
+		// Kernel before 4.18 without discard fields
+		"sda": {reads: 48213, readSecs: 4102938, readTime: 89213, writes: 12093, writeSecs: 1839201, writeTime: 72019},
+		"sdb": {reads: 4294967295, readSecs: 18446744073709551615, readTime: 10},
+	}
+	for name, want := range tests {
+		if got := counters[name]; got != want {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+	if len(counters) != 7 {
+		t.Errorf("expected 7 devices, got %d", len(counters))
+	}
+}
+
+func TestParseDiskstatsInvalid(t *testing.T) {
+	line := "   8       0 sda 48213 x 4102938 89213 12093 8812 1839201 72019 0 61920 161232\n"
+	if _, err := parseDiskstats(strings.NewReader(line)); err == nil {
+		t.Error("expected error on invalid counter")
+	}
+}
+
+func TestParseMounts(t *testing.T) {
+	f, err := os.Open("testdata/mounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	mounts, err := parseMounts(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []mount{
+		{"/dev/mapper/root", "/", "ext4"},
+		{"proc", "/proc", "proc"},
+		{"overlay", "/var/lib/docker/overlay2/8f2e/merged", "overlay"},
+		{"/dev/nvme0n1p1", "/boot/efi", "vfat"},
+		{"/dev/sdb1", "/media/user/My Passport", "ext4"},
+		{"//nas/share", "/mnt/tab\tand\\backslash", "cifs"},
+	}
+	if !reflect.DeepEqual(mounts, want) {
+		t.Errorf("got %+v, want %+v", mounts, want)
+	}
+	// Overlay is the root filesystem of containers, so it must be watched
+	if pseudoFs["overlay"] {
+		t.Error("overlay must not be skipped as pseudo filesystem")
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	tests := map[string]string{
+		"/mnt/data":            "/mnt/data",
+		`/mnt/My\040Disk`:      "/mnt/My Disk",
+		`/mnt/a\011b\012c`:     "/mnt/a\tb\nc",
+		`/mnt/back\134slash`:   `/mnt/back\slash`,
+		`/mnt/end\040`:         "/mnt/end ",
+		`/mnt/short\04`:        `/mnt/short\04`, // Incomplete escape
+		`/mnt/invalid\089`:     `/mnt/invalid\089`,
+		`/mnt/\342\202\254uro`: "/mnt/€uro",
+		`/mnt/trailing\`:       `/mnt/trailing\`,
+	}
+	for s, want := range tests {
+		if got := unescape(s); got != want {
+			t.Errorf("unescape(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestGetDeviceStats(t *testing.T) {
+	last := ioCounters{reads: 100, readSecs: 2000, readTime: 50, writes: 200, writeSecs: 4000, writeTime: 150}
+	cur := ioCounters{reads: 300, readSecs: 6000, readTime: 250, writes: 400, writeSecs: 8000, writeTime: 550}
+	ds := getDeviceStats("sda", last, cur, 2)
+	want := DeviceStats{
+		Name:       "sda",
+		ReadSpeed:  1024, // 4000 sectors * 512 B / 2s / 1000
+		WriteSpeed: 1024,
+		ReadIOPS:   100,
+		WriteIOPS:  100,
+		Latency:    1.5, // (200 + 400) ms / 400 I/O
+	}
+	if ds != want {
+		t.Errorf("got %+v, want %+v", ds, want)
+	}
+	// Reset counters (e.g. device re-attached) report zero instead of a huge spike
+	if ds := getDeviceStats("sda", cur, last, 2); ds.ReadSpeed != 0 || ds.ReadIOPS != 0 || ds.Latency != 0 {
+		t.Errorf("expected zero after reset, got %+v", ds)
+	}
+}
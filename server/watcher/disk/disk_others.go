@@ -0,0 +1,14 @@
+// +build !linux
+
+package disk
+
+// TODO: Windows/Darwin
+type collector struct{}
+
+func newCollector(w *watcher) *collector {
+	return &collector{}
+}
+
+func (c *collector) collect() (*Stats, error) {
+	return &Stats{}, nil
+}
@@ -0,0 +1,139 @@
+// +build linux
+
+package gpu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lnquy/nights-watch/server/util"
+)
+
+const amdVendorID = "0x1002"
+
+type (
+	// amdBackend reads AMD GPU statistics exposed by the amdgpu driver via sysfs.
+	amdBackend struct {
+		cards []amdCard
+	}
+
+	amdCard struct {
+		drmCard
+		hwmon string // e.g.: /sys/class/drm/card0/device/hwmon/hwmon3
+	}
+)
+
+// newAMDBackend finds all AMD cards under the DRM sysfs root.
+func newAMDBackend(root string) (*amdBackend, error) {
+	cards, err := findCards(root, amdVendorID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("gpu: no AMD card found in %s", root)
+	}
+	b := &amdBackend{}
+	for _, c := range cards {
+		ac := amdCard{drmCard: c}
+		if hwmons, _ := filepath.Glob(filepath.Join(c.device, "hwmon", "hwmon*")); len(hwmons) > 0 {
+			ac.hwmon = hwmons[0]
+		}
+		b.cards = append(b.cards, ac)
+	}
+	return b, nil
+}
+
+func (b *amdBackend) stats() ([]Card, error) {
+	cards := make([]Card, 0, len(b.cards))
+	for _, ac := range b.cards {
+		c, err := ac.stats()
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, c)
+	}
+	return cards, nil
+}
+
+func (ac *amdCard) stats() (Card, error) {
+	c := ac.toCard()
+	c.Name = "AMD"
+	load, err := util.ReadUint(filepath.Join(ac.device, "gpu_busy_percent"))
+	if err != nil {
+		return c, err
+	}
+	c.Load = float64(load)
+	if used, err := util.ReadUint(filepath.Join(ac.device, "mem_info_vram_used")); err == nil {
+		c.Mem = used / 1000000
+	}
+	if total, err := util.ReadUint(filepath.Join(ac.device, "mem_info_vram_total")); err == nil {
+		c.MemTotal = total / 1000000
+	}
+	c.Clock, c.ClockMax = readDPMClocks(filepath.Join(ac.device, "pp_dpm_sclk"))
+	c.MemClock, _ = readDPMClocks(filepath.Join(ac.device, "pp_dpm_mclk"))
+	if ac.hwmon == "" {
+		return c, nil
+	}
+	if temp, err := util.ReadUint(filepath.Join(ac.hwmon, "temp1_input")); err == nil {
+		c.Temp = float64(temp) / 1000 // Millidegree Celsius
+	}
+	if pwm, err := util.ReadUint(filepath.Join(ac.hwmon, "pwm1")); err == nil {
+		pwmMax, err := util.ReadUint(filepath.Join(ac.hwmon, "pwm1_max"))
+		if err != nil || pwmMax == 0 {
+			pwmMax = 255
+		}
+		c.Fan = float64(pwm) / float64(pwmMax) * 100
+	}
+	// Older kernels only expose the average power
+	for _, f := range []string{"power1_average", "power1_input"} {
+		if power, err := util.ReadUint(filepath.Join(ac.hwmon, f)); err == nil {
+			c.Power = float64(power) / 1000000 // Microwatts
+			break
+		}
+	}
+	if limit, err := util.ReadUint(filepath.Join(ac.hwmon, "power1_cap")); err == nil {
+		c.PowerLimit = float64(limit) / 1000000
+	}
+	return c, nil
+}
+
+// readDPMClocks returns the current and maximum clocks (MHz) from the DPM states file, where the
+// current state is marked with an asterisk, e.g.:
+//
+//	0: 500Mhz
+//	1: 1100Mhz *
+//	2: 1800Mhz
+func readDPMClocks(fp string) (cur, max uint64) {
+	b, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return 0, 0
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		clock, err := strconv.ParseUint(strings.TrimSuffix(strings.ToLower(fields[1]), "mhz"), 10, 64)
+		if err != nil {
+			continue
+		}
+		if clock > max {
+			max = clock
+		}
+		if len(fields) > 2 && fields[2] == "*" {
+			cur = clock
+		}
+	}
+	return cur, max
+}
+
+func (b *amdBackend) close() error {
+	return nil
+}
+
+func (b *amdBackend) processes() (map[string][]Process, error) {
+	return drmProcesses("amdgpu", []string{"drm-memory-vram"})
+}
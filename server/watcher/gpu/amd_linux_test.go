@@ -0,0 +1,126 @@
+// +build linux
+
+package gpu
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Fixtures of DRM sysfs in testdata: card0 and card2 are AMD, card1 is Intel and card0-DP-1 is a connector.
+// Device symlinks point to PCI device directories which are named by bus ID in sysfs (e.g.: 0000:03:00.0),
+// but ':' is not allowed in file names on all platforms so they are named amd0, amd1 and intel0 instead.
+const testDRMRoot = "testdata/drm"
+
+func TestAMDBackend(t *testing.T) {
+	b, err := newAMDBackend(testDRMRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cards, err := b.stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Card{
+		{Index: 0, UUID: "7f3a2c1d0e5b4a69", BusID: "amd0", Name: "AMD", Metrics: Metrics{
+			Load: 37, Mem: 2147, MemTotal: 8573, Temp: 54, Fan: 40, Power: 48, PowerLimit: 186,
+			Clock: 1100, ClockMax: 1800, MemClock: 1000,
+		}},
+		// No fan and no power1_average
+		{Index: 2, BusID: "amd1", Name: "AMD", Metrics: Metrics{
+			Load: 0, Mem: 524, MemTotal: 4278, Temp: 41, Power: 15, Clock: 200, ClockMax: 2200,
+		}},
+	}
+	if len(cards) != len(want) {
+		t.Fatalf("got %d cards, want %d: %+v", len(cards), len(want), cards)
+	}
+	for i, c := range cards {
+		checkCard(t, c, want[i])
+	}
+}
+
+func TestAMDBackendNoCard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Only an Intel card
+	if err := os.Mkdir(filepath.Join(dir, "card0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(mustAbs(t, "testdata/devices/intel0"), filepath.Join(dir, "card0", "device")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newAMDBackend(dir); err == nil {
+		t.Error("expected error without AMD card")
+	}
+	if _, err := newAMDBackend(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error without DRM root")
+	}
+}
+
+func TestReadDPMClocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dpm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		content  string
+		cur, max uint64
+	}{
+		{"0: 500Mhz \n1: 1100Mhz *\n2: 1800Mhz \n", 1100, 1800},
+		{"0: 300Mhz *\n1: 2100Mhz \n", 300, 2100},
+		{"0: 96Mhz \n1: 456Mhz \n2: 1000MHz *\n", 1000, 1000},
+		{"0: 500Mhz \n1: 1800Mhz \n", 0, 1800},              // No current state, e.g.: GPU is suspended
+		{"S: 19Mhz *\n0: 500Mhz \n1: 2400Mhz \n", 19, 2400}, // Sleep state of RDNA cards
+		{"garbage\n0: fastMhz *\n", 0, 0},
+		{"", 0, 0},
+	}
+	for i, tt := range tests {
+		fp := filepath.Join(dir, "pp_dpm_sclk")
+		if err := ioutil.WriteFile(fp, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if cur, max := readDPMClocks(fp); cur != tt.cur || max != tt.max {
+			t.Errorf("%d: got %d/%d, want %d/%d", i, cur, max, tt.cur, tt.max)
+		}
+	}
+	if cur, max := readDPMClocks(filepath.Join(dir, "missing")); cur != 0 || max != 0 {
+		t.Errorf("missing file: got %d/%d, want 0/0", cur, max)
+	}
+}
+
+// checkCard compares the cards with a small tolerance for float metrics.
+func checkCard(t *testing.T, got, want Card) {
+	t.Helper()
+	floats := [][2]float64{
+		{got.Load, want.Load}, {got.Temp, want.Temp}, {got.Fan, want.Fan},
+		{got.Power, want.Power}, {got.PowerLimit, want.PowerLimit},
+	}
+	for _, f := range floats {
+		if math.Abs(f[0]-f[1]) > 0.01 {
+			t.Errorf("card %d: got %+v, want %+v", want.Index, got, want)
+			return
+		}
+	}
+	g, w := got, want
+	g.Load, g.Temp, g.Fan, g.Power, g.PowerLimit = 0, 0, 0, 0, 0
+	w.Load, w.Temp, w.Fan, w.Power, w.PowerLimit = 0, 0, 0, 0, 0
+	if g.Index != w.Index || g.UUID != w.UUID || g.BusID != w.BusID || g.Name != w.Name ||
+		g.Mem != w.Mem || g.MemTotal != w.MemTotal || g.Clock != w.Clock || g.ClockMax != w.ClockMax || g.MemClock != w.MemClock {
+		t.Errorf("card %d: got %+v, want %+v", want.Index, got, want)
+	}
+}
+
+func mustAbs(t *testing.T, fp string) string {
+	abs, err := filepath.Abs(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return abs
+}
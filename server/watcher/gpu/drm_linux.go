@@ -0,0 +1,63 @@
+// +build linux
+
+package gpu
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/lnquy/nights-watch/server/util"
+)
+
+var (
+	drmRoot   = "/sys/class/drm"
+	cardRegex = regexp.MustCompile(`^card([0-9]+)$`) // Skip connectors, e.g.: card0-DP-1
+)
+
+// drmCard is a GPU card found under the DRM sysfs root.
+type drmCard struct {
+	index  int
+	dir    string // e.g.: /sys/class/drm/card0
+	device string // e.g.: /sys/class/drm/card0/device
+	busID  string // e.g.: 0000:03:00.0
+}
+
+// findCards returns all DRM cards of a vendor.
+func findCards(root, vendorID string) ([]drmCard, error) {
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	cards := make([]drmCard, 0)
+	for _, e := range entries {
+		m := cardRegex.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		c := drmCard{
+			dir:    filepath.Join(root, e.Name()),
+			device: filepath.Join(root, e.Name(), "device"),
+		}
+		if util.ReadString(filepath.Join(c.device, "vendor")) != vendorID {
+			continue
+		}
+		c.index, _ = strconv.Atoi(m[1])
+		// Device is a symlink to the PCI device directory which is named by its bus ID
+		if p, err := filepath.EvalSymlinks(c.device); err == nil {
+			c.busID = filepath.Base(p)
+		}
+		cards = append(cards, c)
+	}
+	return cards, nil
+}
+
+func (c *drmCard) toCard() Card {
+	uuid := util.ReadString(filepath.Join(c.device, "unique_id")) // Not all cards support unique ID
+	return Card{
+		Index: c.index,
+		UUID:  uuid,
+		BusID: c.busID,
+	}
+}
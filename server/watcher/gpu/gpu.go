@@ -2,7 +2,13 @@ package gpu
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 type GPUVendor string
@@ -10,21 +16,311 @@ type GPUVendor string
 const (
 	NVIDIA GPUVendor = "nvidia"
 	AMD    GPUVendor = "amd"
+	Intel  GPUVendor = "intel"
+)
+
+// Aggregation determines how statistics of multiple cards are combined into one.
+type Aggregation string
+
+const (
+	Max Aggregation = "max"
+	Sum Aggregation = "sum"
+	Avg Aggregation = "avg"
 )
 
+// errNoSample is returned by backends which sample the cards in background when there's no recent sample,
+// e.g.: before the first sample.
+var errNoSample = errors.New("gpu: no recent sample of cards")
+
+// RetryInterval is the duration to wait before detecting the GPU backend again after a failure.
+var RetryInterval = 30 * time.Second
+
+// ProcessInterval is the minimum duration between listing the processes using cards since it's expensive,
+// e.g.: reading fdinfo of all processes or running nvidia-smi.
+var ProcessInterval = 10 * time.Second
+
 type (
 	Watcher interface {
 		GetStats(ctx context.Context, interval time.Duration, vendor GPUVendor) <-chan *Stats
 	}
 
+	// Stats holds the aggregated metrics of all watched cards and the metrics of each card.
+	// If the GPU backend is not available, only the reason is reported.
 	Stats struct {
-		Load float64
-		Mem  uint64
+		Available bool   `json:"available"`
+		Reason    string `json:"reason,omitempty"`
+		Metrics
+		Cards []Card `json:"cards"`
 	}
 
-	watcher struct{}
+	Metrics struct {
+		Load     float64 `json:"load"`
+		Mem      uint64  `json:"mem"`      // Used memory in MB
+		MemTotal uint64  `json:"memTotal"` // MB
+		Temp     float64 `json:"temp"`     // °C
+		Fan      float64 `json:"fan"`      // Percent
+		Power    float64 `json:"power"`    // Watts
+		Clock    uint64  `json:"clock"`    // Current graphics clock in MHz
+		ClockMax uint64  `json:"clockMax"` // MHz
+		MemClock uint64  `json:"memClock"` // Current memory clock in MHz
+
+		PowerLimit float64  `json:"powerLimit"` // Watts
+		Throttle   []string `json:"throttle"`   // Active clocks throttle reasons, e.g.: thermal, power_cap
+	}
+
+	Card struct {
+		Index int    `json:"index"`
+		UUID  string `json:"uuid"`
+		BusID string `json:"busId"` // PCI bus ID, e.g.: 0000:01:00.0
+		Name  string `json:"name"`
+		Metrics
+		Processes []Process `json:"processes,omitempty"` // Sorted by memory usage
+	}
+
+	// Process holds the GPU usage of a process on a card.
+	Process struct {
+		PID  int    `json:"pid"`
+		Name string `json:"name"`
+		User string `json:"user"`
+		Mem  uint64 `json:"mem"`  // MB
+		Type string `json:"type"` // compute or graphics
+	}
+
+	// backend collects statistics from all GPU cards of a specific vendor.
+	backend interface {
+		stats() ([]Card, error)
+		close() error
+	}
+
+	// processLister is implemented by backends which can list processes using the cards.
+	// Processes are grouped by card ID (index, UUID or PCI bus ID).
+	processLister interface {
+		processes() (map[string][]Process, error)
+	}
+
+	// processCache holds the processes latest listed by a backend.
+	processCache struct {
+		procs    map[string][]Process
+		listedAt time.Time
+	}
+
+	watcher struct {
+		selected    []string
+		aggregation Aggregation
+		processes   bool
+		newBackend  func(vendor GPUVendor, interval time.Duration) (backend, error) // Detects the backend by vendor
+	}
 )
 
-func NewWatcher() Watcher {
-	return &watcher{}
+// NewWatcher returns a GPU watcher which only watches on the selected cards (all cards if empty).
+// Cards can be selected by index, UUID or PCI bus ID.
+// If processes is true, processes using the cards are listed as well.
+func NewWatcher(selected []string, aggregation Aggregation, processes bool) Watcher {
+	if aggregation == "" {
+		aggregation = Max
+	}
+	return &watcher{
+		selected:    selected,
+		aggregation: aggregation,
+		processes:   processes,
+		newBackend:  newBackend,
+	}
+}
+
+// GetStats periodically get GPU statistics from system and returns that value to a Stats channel.
+// Currently supports for 3 GPU vendors on Linux:
+//   - NVIDIA: Get stats via NVML binding, or nvidia-smi if NVML is not usable.
+//   - AMD: Get stats from amdgpu driver via sysfs.
+//   - Intel: Get stats from i915 driver via PMU perf counters and sysfs.
+//
+// If the backend fails, an unavailable Stats is returned and the backend is detected again after RetryInterval.
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration, vendor GPUVendor) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+
+	go func() {
+		logrus.Infof("watcher: GPU watcher started")
+		var b backend
+		var retryAt time.Time
+		var pc processCache
+		for {
+			select {
+			case <-ticker.C:
+				if b == nil {
+					if time.Now().Before(retryAt) {
+						continue
+					}
+					var err error
+					if b, err = w.newBackend(vendor, interval); err != nil {
+						logrus.Errorf("gpu: GPU watcher is unavailable, retry in %s: %s", RetryInterval, err)
+						retryAt = time.Now().Add(RetryInterval)
+						statsChan <- &Stats{Reason: err.Error()}
+						continue
+					}
+				}
+				cards, err := b.stats()
+				if err == errNoSample { // Backend is still fine
+					statsChan <- &Stats{Reason: err.Error()}
+					continue
+				}
+				if err != nil {
+					logrus.Errorf("gpu: failed to get GPU stats, retry in %s: %s", RetryInterval, err)
+					closeBackend(b)
+					b, retryAt, pc = nil, time.Now().Add(RetryInterval), processCache{}
+					statsChan <- &Stats{Reason: err.Error()}
+					continue
+				}
+				if w.processes {
+					attachProcesses(cards, pc.get(b))
+				}
+				statsChan <- w.getStats(cards)
+			case <-ctx.Done():
+				if b != nil {
+					closeBackend(b)
+				}
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: GPU watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
+
+func closeBackend(b backend) {
+	if err := b.close(); err != nil {
+		logrus.Errorf("gpu: failed to close GPU backend: %s", err)
+	}
+}
+
+// get lists the processes using cards if the backend supports it, or returns the cached processes
+// if they were listed within ProcessInterval.
+func (pc *processCache) get(b backend) map[string][]Process {
+	if time.Since(pc.listedAt) < ProcessInterval {
+		return pc.procs
+	}
+	pc.listedAt = time.Now()
+	pc.procs = nil
+	pl, ok := b.(processLister)
+	if !ok {
+		return nil
+	}
+	procs, err := pl.processes()
+	if err != nil {
+		logrus.Debugf("gpu: failed to list GPU processes: %s", err)
+		return nil
+	}
+	pc.procs = procs
+	return procs
+}
+
+// attachProcesses attaches the processes to the cards they are using.
+func attachProcesses(cards []Card, procs map[string][]Process) {
+	for i := range cards {
+		for id, ps := range procs {
+			if cards[i].Match(id) {
+				cards[i].Processes = append(cards[i].Processes, ps...)
+			}
+		}
+		sort.Slice(cards[i].Processes, func(x, y int) bool {
+			return cards[i].Processes[x].Mem > cards[i].Processes[y].Mem
+		})
+	}
+}
+
+// Match checks if the card can be identified by the id (index, UUID or PCI bus ID) or not.
+func (c *Card) Match(id string) bool {
+	id = strings.TrimSpace(id)
+	if id == strconv.Itoa(c.Index) {
+		return true
+	}
+	if c.UUID != "" && strings.EqualFold(id, c.UUID) {
+		return true
+	}
+	// NVIDIA reports 8 digits PCI domain (00000000:01:00.0) while sysfs uses 4 digits (0000:01:00.0)
+	return c.BusID != "" && strings.EqualFold(strings.TrimLeft(id, "0"), strings.TrimLeft(c.BusID, "0"))
+}
+
+// getStats filters the selected cards and aggregates their metrics.
+func (w *watcher) getStats(cards []Card) *Stats {
+	stats := &Stats{Available: true, Cards: make([]Card, 0, len(cards))}
+	for _, c := range cards {
+		if w.isSelected(&c) {
+			stats.Cards = append(stats.Cards, c)
+		}
+	}
+	if len(stats.Cards) == 0 {
+		return stats
+	}
+
+	for i, c := range stats.Cards {
+		m := &stats.Metrics
+		m.Throttle = appendMissing(m.Throttle, c.Throttle...)
+		if w.aggregation == Max && i > 0 {
+			m.Load, m.Temp, m.Fan, m.Power = maxFloat(m.Load, c.Load), maxFloat(m.Temp, c.Temp), maxFloat(m.Fan, c.Fan), maxFloat(m.Power, c.Power)
+			m.Mem, m.MemTotal, m.Clock, m.ClockMax = maxUint(m.Mem, c.Mem), maxUint(m.MemTotal, c.MemTotal), maxUint(m.Clock, c.Clock), maxUint(m.ClockMax, c.ClockMax)
+			m.MemClock, m.PowerLimit = maxUint(m.MemClock, c.MemClock), maxFloat(m.PowerLimit, c.PowerLimit)
+			continue
+		}
+		if w.aggregation == Max {
+			throttle := m.Throttle
+			*m = c.Metrics
+			m.Throttle = throttle
+			continue
+		}
+		m.Load, m.Temp, m.Fan, m.Power = m.Load+c.Load, m.Temp+c.Temp, m.Fan+c.Fan, m.Power+c.Power
+		m.Mem, m.MemTotal, m.Clock, m.ClockMax = m.Mem+c.Mem, m.MemTotal+c.MemTotal, m.Clock+c.Clock, m.ClockMax+c.ClockMax
+		m.MemClock, m.PowerLimit = m.MemClock+c.MemClock, m.PowerLimit+c.PowerLimit
+	}
+	if w.aggregation == Avg {
+		m, n := &stats.Metrics, len(stats.Cards)
+		m.Load, m.Temp, m.Fan, m.Power = m.Load/float64(n), m.Temp/float64(n), m.Fan/float64(n), m.Power/float64(n)
+		m.Mem, m.MemTotal, m.Clock, m.ClockMax = m.Mem/uint64(n), m.MemTotal/uint64(n), m.Clock/uint64(n), m.ClockMax/uint64(n)
+		m.MemClock, m.PowerLimit = m.MemClock/uint64(n), m.PowerLimit/float64(n)
+	}
+	return stats
+}
+
+func (w *watcher) isSelected(c *Card) bool {
+	if len(w.selected) == 0 {
+		return true
+	}
+	for _, id := range w.selected {
+		if c.Match(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendMissing appends the values which are not in the slice yet.
+func appendMissing(slice []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, s := range slice {
+			if s == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			slice = append(slice, v)
+		}
+	}
+	return slice
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func maxUint(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
 }
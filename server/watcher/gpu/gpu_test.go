@@ -0,0 +1,222 @@
+package gpu
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeBackend returns the cards after the first noSamples calls of stats, or fails the first failures calls.
+type fakeBackend struct {
+	cards     []Card
+	noSamples int
+	failures  int
+	procs     map[string][]Process
+	closed    int
+}
+
+func (b *fakeBackend) stats() ([]Card, error) {
+	if b.noSamples > 0 {
+		b.noSamples--
+		return nil, errNoSample
+	}
+	if b.failures > 0 {
+		b.failures--
+		return nil, errors.New("card is gone")
+	}
+	cards := make([]Card, len(b.cards))
+	copy(cards, b.cards)
+	return cards, nil
+}
+
+func (b *fakeBackend) close() error {
+	b.closed++
+	return nil
+}
+
+func (b *fakeBackend) processes() (map[string][]Process, error) {
+	return b.procs, nil
+}
+
+func TestGetStatsRetry(t *testing.T) {
+	defer func(d time.Duration) { RetryInterval = d }(RetryInterval)
+	RetryInterval = 0
+
+	failing := &fakeBackend{failures: 1}
+	working := &fakeBackend{cards: []Card{{Index: 0, Name: "fake", Metrics: Metrics{Load: 42}}}}
+	detected := 0
+	w := NewWatcher(nil, Max, false).(*watcher)
+	w.newBackend = func(vendor GPUVendor, interval time.Duration) (backend, error) {
+		detected++
+		switch detected {
+		case 1:
+			return nil, errors.New("no card found")
+		case 2:
+			return failing, nil
+		default:
+			return working, nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	statsChan := w.GetStats(ctx, time.Millisecond, NVIDIA)
+	want := []Stats{
+		{Reason: "no card found"},
+		{Reason: "card is gone"},
+		{Available: true, Metrics: Metrics{Load: 42}, Cards: working.cards},
+	}
+	for i, ws := range want {
+		s := <-statsChan
+		if s.Available != ws.Available || s.Reason != ws.Reason || s.Load != ws.Load || len(s.Cards) != len(ws.Cards) {
+			t.Errorf("%d: got %+v, want %+v", i, s, ws)
+		}
+	}
+	cancel()
+	for range statsChan {
+	}
+	if failing.closed != 1 || working.closed != 1 {
+		t.Errorf("got closed failing backend %d times and working backend %d times, want 1", failing.closed, working.closed)
+	}
+	if detected != 3 {
+		t.Errorf("got %d detections, want 3", detected)
+	}
+}
+
+func TestGetStatsNoSample(t *testing.T) {
+	b := &fakeBackend{noSamples: 2, cards: []Card{{Index: 0, Metrics: Metrics{Load: 42}}}}
+	detected := 0
+	w := NewWatcher(nil, Max, false).(*watcher)
+	w.newBackend = func(vendor GPUVendor, interval time.Duration) (backend, error) {
+		detected++
+		return b, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	statsChan := w.GetStats(ctx, time.Millisecond, NVIDIA)
+	for i := 0; i < 2; i++ {
+		if s := <-statsChan; s.Available || s.Reason != errNoSample.Error() {
+			t.Errorf("%d: got %+v, want unavailable", i, s)
+		}
+	}
+	if s := <-statsChan; !s.Available || s.Load != 42 {
+		t.Errorf("got %+v, want available", s)
+	}
+	cancel()
+	for range statsChan {
+	}
+	if detected != 1 || b.closed != 1 {
+		t.Errorf("got %d detections and %d closes, want backend is kept until stopped", detected, b.closed)
+	}
+}
+
+func TestGetStatsRetryInterval(t *testing.T) {
+	defer func(d time.Duration) { RetryInterval = d }(RetryInterval)
+	RetryInterval = time.Hour
+
+	detected := 0
+	w := NewWatcher(nil, Max, false).(*watcher)
+	w.newBackend = func(vendor GPUVendor, interval time.Duration) (backend, error) {
+		detected++
+		return nil, errors.New("no card found")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	statsChan := w.GetStats(ctx, time.Millisecond, AMD)
+	if s := <-statsChan; s.Available || s.Reason != "no card found" {
+		t.Errorf("got %+v, want unavailable", s)
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	for s := range statsChan {
+		t.Errorf("got %+v before retry interval", s)
+	}
+	if detected != 1 {
+		t.Errorf("got %d detections, want 1", detected)
+	}
+}
+
+func TestAggregation(t *testing.T) {
+	cards := []Card{
+		{Index: 0, UUID: "GPU-a", BusID: "00000000:01:00.0", Metrics: Metrics{
+			Load: 20, Mem: 1000, MemTotal: 8000, Temp: 60, Fan: 30, Power: 100, Clock: 1500, ClockMax: 2000,
+			MemClock: 5000, PowerLimit: 200, Throttle: []string{"power_cap"},
+		}},
+		{Index: 1, UUID: "GPU-b", BusID: "0000:02:00.0", Metrics: Metrics{
+			Load: 60, Mem: 3000, MemTotal: 4000, Temp: 40, Fan: 50, Power: 50, Clock: 500, ClockMax: 1000,
+			MemClock: 3000, PowerLimit: 100, Throttle: []string{"thermal", "power_cap"},
+		}},
+	}
+	tests := []struct {
+		aggregation Aggregation
+		selected    []string
+		want        Metrics
+		cards       int
+	}{
+		{Max, nil, Metrics{Load: 60, Mem: 3000, MemTotal: 8000, Temp: 60, Fan: 50, Power: 100, Clock: 1500, ClockMax: 2000,
+			MemClock: 5000, PowerLimit: 200, Throttle: []string{"power_cap", "thermal"}}, 2},
+		{Sum, nil, Metrics{Load: 80, Mem: 4000, MemTotal: 12000, Temp: 100, Fan: 80, Power: 150, Clock: 2000, ClockMax: 3000,
+			MemClock: 8000, PowerLimit: 300, Throttle: []string{"power_cap", "thermal"}}, 2},
+		{Avg, nil, Metrics{Load: 40, Mem: 2000, MemTotal: 6000, Temp: 50, Fan: 40, Power: 75, Clock: 1000, ClockMax: 1500,
+			MemClock: 4000, PowerLimit: 150, Throttle: []string{"power_cap", "thermal"}}, 2},
+		{Max, []string{"1"}, cards[1].Metrics, 1},
+		{Sum, []string{"gpu-a"}, cards[0].Metrics, 1},        // UUID is case insensitive
+		{Avg, []string{"0000:01:00.0"}, cards[0].Metrics, 1}, // PCI domain digits differ
+		{Max, []string{"3"}, Metrics{}, 0},
+	}
+	for i, tt := range tests {
+		w := NewWatcher(tt.selected, tt.aggregation, false).(*watcher)
+		s := w.getStats(cards)
+		if !s.Available || len(s.Cards) != tt.cards {
+			t.Errorf("%d: got %d cards (available: %v), want %d", i, len(s.Cards), s.Available, tt.cards)
+			continue
+		}
+		if !reflect.DeepEqual(s.Metrics, tt.want) {
+			t.Errorf("%d: got %+v, want %+v", i, s.Metrics, tt.want)
+		}
+	}
+}
+
+func TestAttachProcesses(t *testing.T) {
+	procs := map[string][]Process{
+		"GPU-a":        {{PID: 1, Mem: 10}, {PID: 2, Mem: 300}},
+		"0000:02:00.0": {{PID: 3, Mem: 20}},
+		"1":            {{PID: 4, Mem: 50}},
+	}
+	cards := []Card{{Index: 0, UUID: "GPU-a"}, {Index: 1, BusID: "00000000:02:00.0"}, {Index: 2}}
+	attachProcesses(cards, procs)
+	want := [][]int{{2, 1}, {4, 3}, nil}
+	for i, c := range cards {
+		var pids []int
+		for _, p := range c.Processes {
+			pids = append(pids, p.PID)
+		}
+		if !reflect.DeepEqual(pids, want[i]) {
+			t.Errorf("card %d: got processes %v, want %v", i, pids, want[i])
+		}
+	}
+}
+
+func TestProcessCache(t *testing.T) {
+	defer func(d time.Duration) { ProcessInterval = d }(ProcessInterval)
+	ProcessInterval = time.Hour
+
+	b := &fakeBackend{procs: map[string][]Process{"0": {{PID: 1}}}}
+	var pc processCache
+	if got := pc.get(b); !reflect.DeepEqual(got, b.procs) {
+		t.Errorf("got %+v, want %+v", got, b.procs)
+	}
+	listed := b.procs
+	b.procs = map[string][]Process{"0": {{PID: 2}}}
+	if got := pc.get(b); !reflect.DeepEqual(got, listed) {
+		t.Errorf("got %+v within process interval, want cached %+v", got, listed)
+	}
+
+	ProcessInterval = 0
+	if got := pc.get(b); !reflect.DeepEqual(got, b.procs) {
+		t.Errorf("got %+v after process interval, want %+v", got, b.procs)
+	}
+	// Backend doesn't list processes
+	if got := pc.get(struct{ backend }{b}); got != nil {
+		t.Errorf("got %+v, want nil", got)
+	}
+}
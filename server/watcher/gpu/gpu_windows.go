@@ -3,32 +3,11 @@
 package gpu
 
 import (
-	"context"
+	"fmt"
 	"time"
-
-	"github.com/sirupsen/logrus"
 )
 
-func (w *watcher) GetStats(ctx context.Context, interval time.Duration, vendor GPUVendor) <-chan *Stats {
-	ticker := time.NewTicker(interval)
-	statsChan := make(chan *Stats, 10)
-	stats := &Stats{}
-	go func() {
-		logrus.Infof("watcher: GPU watcher started")
-		for {
-			select {
-			case <-ticker.C:
-				// TODO
-				stats.Load = 0
-				stats.Mem = 0
-				statsChan <- stats
-			case <-ctx.Done():
-				close(statsChan)
-				ticker.Stop()
-				logrus.Infof("watcher: GPU watcher stopped")
-				return
-			}
-		}
-	}()
-	return statsChan
+// TODO
+func newBackend(vendor GPUVendor, interval time.Duration) (backend, error) {
+	return nil, fmt.Errorf("gpu: GPU watcher is not supported on windows yet")
 }
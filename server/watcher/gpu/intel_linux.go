@@ -0,0 +1,238 @@
+// +build linux
+
+package gpu
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/lnquy/nights-watch/server/util"
+	"golang.org/x/sys/unix"
+)
+
+const intelVendorID = "0x8086"
+
+var pmuRoot = "/sys/bus/event_source/devices/i915"
+
+type (
+	// intelBackend reads Intel GPU statistics exposed by the i915 driver.
+	// Render engine busy time is read from the i915 PMU perf counter if possible (requires CAP_PERFMON or root),
+	// otherwise it's estimated from the RC6 (GPU idle) residency in sysfs.
+	intelBackend struct {
+		cards []*intelCard
+	}
+
+	intelCard struct {
+		drmCard
+		perfFd int    // -1 if PMU is not available
+		rc6    string // RC6 residency (ms) sysfs file
+
+		lastBusy uint64 // ns
+		lastIdle uint64 // ms, RC6 only
+		lastTime time.Time
+	}
+)
+
+// newIntelBackend finds all Intel cards under the DRM sysfs root.
+// The PMU of the first card is named i915 while others are suffixed by their PCI bus ID,
+// e.g.: i915_0000_03_00.0.
+func newIntelBackend(root, pmu string) (*intelBackend, error) {
+	cards, err := findCards(root, intelVendorID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("gpu: no Intel card found in %s", root)
+	}
+	b := &intelBackend{}
+	for i, c := range cards {
+		pmus := []string{pmu + "_" + strings.Replace(c.busID, ":", "_", -1)}
+		if i == 0 {
+			pmus = append(pmus, pmu)
+		}
+		ic, err := newIntelCard(c, pmus)
+		if err != nil {
+			b.close()
+			return nil, err
+		}
+		b.cards = append(b.cards, ic)
+	}
+	return b, nil
+}
+
+func newIntelCard(c drmCard, pmus []string) (*intelCard, error) {
+	ic := &intelCard{
+		drmCard: c,
+		perfFd:  -1,
+	}
+	var err error
+	for _, pmu := range pmus {
+		if ic.perfFd, err = openPMUCounter(pmu, "rcs0-busy"); err == nil {
+			return ic, nil
+		}
+	}
+	// Kernel moved RC6 residency to per GT directory since multiple GTs support
+	for _, f := range []string{"gt/gt0/rc6_residency_ms", "power/rc6_residency_ms"} {
+		if _, err := util.ReadUint(filepath.Join(c.dir, f)); err == nil {
+			ic.rc6 = filepath.Join(c.dir, f)
+			return ic, nil
+		}
+	}
+	return nil, fmt.Errorf("gpu: failed to read render engine busy time of Intel card %d: %s", c.index, err)
+}
+
+func (b *intelBackend) stats() ([]Card, error) {
+	cards := make([]Card, 0, len(b.cards))
+	for _, ic := range b.cards {
+		c, err := ic.stats()
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, c)
+	}
+	return cards, nil
+}
+
+func (b *intelBackend) close() error {
+	var err error
+	for _, ic := range b.cards {
+		if ic.perfFd >= 0 {
+			if e := unix.Close(ic.perfFd); e != nil {
+				err = e
+			}
+			ic.perfFd = -1
+		}
+	}
+	return err
+}
+
+// processes lists the processes using Intel cards, integrated cards have no dedicated memory
+// so resident memory of all regions is reported.
+func (b *intelBackend) processes() (map[string][]Process, error) {
+	return drmProcesses("i915", []string{"drm-resident-"})
+}
+
+func (ic *intelCard) stats() (Card, error) {
+	c := ic.toCard()
+	c.Name = "Intel"
+	now := time.Now()
+	busy, err := ic.readBusy(now)
+	if err != nil {
+		return c, err
+	}
+	// First sample is only used to initialize the counter
+	if !ic.lastTime.IsZero() && busy >= ic.lastBusy {
+		if elapsed := now.Sub(ic.lastTime); elapsed > 0 {
+			c.Load = float64(busy-ic.lastBusy) / float64(elapsed) * 100
+			if c.Load > 100 {
+				c.Load = 100
+			}
+		}
+	}
+	ic.lastBusy, ic.lastTime = busy, now
+
+	if clock, err := util.ReadUint(filepath.Join(ic.dir, "gt_act_freq_mhz")); err == nil {
+		c.Clock = clock
+	} else if clock, err := util.ReadUint(filepath.Join(ic.dir, "gt_cur_freq_mhz")); err == nil {
+		c.Clock = clock
+	}
+	if clockMax, err := util.ReadUint(filepath.Join(ic.dir, "gt_max_freq_mhz")); err == nil {
+		c.ClockMax = clockMax
+	}
+	c.Throttle = readThrottleReasons(filepath.Join(ic.dir, "gt", "gt0"))
+	return c, nil
+}
+
+// readThrottleReasons returns the active throttle reasons of a GT, each reason is exposed as a
+// throttle_reason_<name> file which contains 1 if active.
+func readThrottleReasons(gt string) []string {
+	reasons := make([]string, 0)
+	files, _ := filepath.Glob(filepath.Join(gt, "throttle_reason_*"))
+	for _, f := range files {
+		name := strings.TrimPrefix(filepath.Base(f), "throttle_reason_")
+		if name == "status" { // Any of reasons is active
+			continue
+		}
+		if v, err := util.ReadUint(f); err == nil && v == 1 {
+			reasons = append(reasons, name)
+		}
+	}
+	return reasons
+}
+
+// readBusy returns the cumulative busy time (ns) of the render engine.
+// For RC6, busy time is the wall time minus the idle (RC6) time since the first sample.
+func (ic *intelCard) readBusy(now time.Time) (uint64, error) {
+	if ic.perfFd >= 0 {
+		return readCounter(ic.perfFd)
+	}
+
+	idle, err := util.ReadUint(ic.rc6)
+	if err != nil {
+		return 0, err
+	}
+	lastIdle := ic.lastIdle
+	ic.lastIdle = idle
+	if ic.lastTime.IsZero() {
+		return 0, nil
+	}
+	elapsed := uint64(now.Sub(ic.lastTime))
+	idleDelta := elapsed // Counter reset, consider the GPU was idle
+	if idle >= lastIdle && (idle-lastIdle)*uint64(time.Millisecond) < elapsed {
+		idleDelta = (idle - lastIdle) * uint64(time.Millisecond)
+	}
+	return ic.lastBusy + elapsed - idleDelta, nil
+}
+
+// readCounter reads the value of a perf counter, which is in native byte order.
+func readCounter(fd int) (uint64, error) {
+	var v uint64
+	n, err := unix.Read(fd, (*[8]byte)(unsafe.Pointer(&v))[:])
+	if err != nil {
+		return 0, err
+	}
+	if n != 8 {
+		return 0, fmt.Errorf("gpu: short read of perf counter: %d bytes", n)
+	}
+	return v, nil
+}
+
+// openPMUCounter opens a perf counter of an i915 PMU event, e.g.: rcs0-busy.
+func openPMUCounter(pmu, event string) (int, error) {
+	pmuType, err := util.ReadUint(filepath.Join(pmu, "type"))
+	if err != nil {
+		return -1, err
+	}
+	config, err := readPMUEventConfig(filepath.Join(pmu, "events", event))
+	if err != nil {
+		return -1, err
+	}
+	// i915 PMU is an uncore PMU so the counter must be opened on a CPU instead of a process
+	cpu := 0
+	if mask := util.ReadString(filepath.Join(pmu, "cpumask")); mask != "" {
+		if c, err := strconv.Atoi(strings.Split(strings.Split(mask, ",")[0], "-")[0]); err == nil {
+			cpu = c
+		}
+	}
+	attr := unix.PerfEventAttr{
+		Type:   uint32(pmuType),
+		Config: config,
+	}
+	attr.Size = uint32(unsafe.Sizeof(attr))
+	// Don't leak the counter to commands and plugins started by other watchers
+	return unix.PerfEventOpen(&attr, -1, cpu, -1, unix.PERF_FLAG_FD_CLOEXEC)
+}
+
+// readPMUEventConfig parses the PMU event config, e.g.: "config=0x0".
+func readPMUEventConfig(fp string) (uint64, error) {
+	for _, term := range strings.Split(util.ReadString(fp), ",") {
+		if kv := strings.SplitN(term, "=", 2); len(kv) == 2 && kv[0] == "config" {
+			return strconv.ParseUint(kv[1], 0, 64)
+		}
+	}
+	return 0, fmt.Errorf("gpu: no config found in PMU event %s", fp)
+}
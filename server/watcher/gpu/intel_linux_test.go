@@ -0,0 +1,174 @@
+// +build linux
+
+package gpu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestIntelBackend(t *testing.T) {
+	// No PMU so the busy time is estimated from RC6 residency
+	b, err := newIntelBackend(testDRMRoot, "testdata/pmu/i915")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+	if len(b.cards) != 1 {
+		t.Fatalf("got %d cards, want 1", len(b.cards))
+	}
+	if ic := b.cards[0]; ic.perfFd != -1 || ic.rc6 != filepath.Join(testDRMRoot, "card1", "gt/gt0/rc6_residency_ms") {
+		t.Errorf("got perf fd %d and RC6 file %s", ic.perfFd, ic.rc6)
+	}
+	cards, err := b.stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 1 {
+		t.Fatalf("got %d cards, want 1", len(cards))
+	}
+	checkCard(t, cards[0], Card{Index: 1, BusID: "intel0", Name: "Intel", Metrics: Metrics{Clock: 650, ClockMax: 1300}})
+	if want := []string{"pl1", "prochot"}; !reflect.DeepEqual(cards[0].Throttle, want) {
+		t.Errorf("got throttle reasons %v, want %v", cards[0].Throttle, want)
+	}
+}
+
+func TestIntelBackendNoCard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := newIntelBackend(dir, "testdata/pmu/i915"); err == nil {
+		t.Error("expected error without Intel card")
+	}
+	// Intel card without PMU and RC6 residency
+	if err := os.Mkdir(filepath.Join(dir, "card0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(mustAbs(t, "testdata/devices/intel0"), filepath.Join(dir, "card0", "device")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newIntelBackend(dir, "testdata/pmu/i915"); err == nil {
+		t.Error("expected error without busy time")
+	}
+}
+
+func TestIntelReadBusyRC6(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rc6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ic := &intelCard{perfFd: -1, rc6: filepath.Join(dir, "rc6_residency_ms")}
+	start := time.Now()
+	tests := []struct {
+		idle    string // ms
+		elapsed time.Duration
+		busy    uint64 // ns
+	}{
+		{"1000", 0, 0},                        // First sample
+		{"1600", time.Second, 400 * 1e6},      // Idle 600ms in 1s
+		{"2600", time.Second, 400 * 1e6},      // Idle for the whole second
+		{"10", time.Second, 400 * 1e6},        // Counter reset is considered as idle
+		{"5000", time.Second, 400 * 1e6},      // Idle longer than elapsed time
+		{"5000", 2 * time.Second, 2400 * 1e6}, // Busy for 2s
+	}
+	now := start
+	for i, tt := range tests {
+		if err := ioutil.WriteFile(ic.rc6, []byte(tt.idle+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(tt.elapsed)
+		busy, err := ic.readBusy(now)
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if busy != tt.busy {
+			t.Errorf("%d: got busy %d, want %d", i, busy, tt.busy)
+		}
+		ic.lastBusy, ic.lastTime = busy, now
+	}
+}
+
+func TestReadCounter(t *testing.T) {
+	var fds [2]int
+	if err := unix.Pipe(fds[:]); err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(fds[0])
+	defer unix.Close(fds[1])
+
+	// Perf counter value is in native byte order
+	want := uint64(0x0102030405060708)
+	if _, err := unix.Write(fds[1], (*[8]byte)(unsafe.Pointer(&want))[:]); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readCounter(fds[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got %#x, want %#x", got, want)
+	}
+
+	if _, err := unix.Write(fds[1], []byte{1, 2, 3, 4}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readCounter(fds[0]); err == nil {
+		t.Error("expected error on short read")
+	}
+}
+
+func TestReadPMUEventConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pmu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		content string
+		config  uint64
+		err     bool
+	}{
+		{"config=0x0\n", 0, false},
+		{"event=0x2,config=0x100000\n", 0x100000, false},
+		{"config=12", 12, false},
+		{"event=0x2\n", 0, true},
+		{"config=zz", 0, true},
+	}
+	for i, tt := range tests {
+		fp := filepath.Join(dir, "rcs0-busy")
+		if err := ioutil.WriteFile(fp, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		config, err := readPMUEventConfig(fp)
+		if (err != nil) != tt.err {
+			t.Errorf("%d: got error %v, want error %v", i, err, tt.err)
+			continue
+		}
+		if config != tt.config {
+			t.Errorf("%d: got config %#x, want %#x", i, config, tt.config)
+		}
+	}
+	if _, err := readPMUEventConfig(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error on missing event")
+	}
+}
+
+func TestReadThrottleReasons(t *testing.T) {
+	if got := readThrottleReasons(filepath.Join(testDRMRoot, "card1", "gt", "gt0")); !reflect.DeepEqual(got, []string{"pl1", "prochot"}) {
+		t.Errorf("got %v", got)
+	}
+	// Older kernels don't expose throttle reasons
+	if got := readThrottleReasons(filepath.Join(testDRMRoot, "card0")); got == nil || len(got) != 0 {
+		t.Errorf("got %v, want empty", got)
+	}
+}
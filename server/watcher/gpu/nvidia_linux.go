@@ -0,0 +1,139 @@
+// +build linux
+
+package gpu
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mindprince/gonvml"
+	"github.com/sirupsen/logrus"
+)
+
+var nvidiaProcRoot = "/proc/driver/nvidia/gpus"
+
+type (
+	// nvidiaBackend gets NVIDIA GPU statistics via NVML binding.
+	nvidiaBackend struct {
+		cards []nvidiaCard
+	}
+
+	nvidiaCard struct {
+		index  int
+		uuid   string
+		busID  string
+		name   string
+		device gonvml.Device
+	}
+)
+
+func newNvidiaBackend() (*nvidiaBackend, error) {
+	if err := gonvml.Initialize(); err != nil {
+		return nil, fmt.Errorf("gpu: failed to initialize NVML: %s", err)
+	}
+	b, err := detectNvidiaCards()
+	if err != nil {
+		gonvml.Shutdown()
+		return nil, err
+	}
+	return b, nil
+}
+
+func detectNvidiaCards() (*nvidiaBackend, error) {
+	devices, err := gonvml.DeviceCount()
+	if err != nil {
+		return nil, fmt.Errorf("gpu: failed to detect NVIDIA card: %s", err)
+	}
+	if devices == 0 {
+		return nil, fmt.Errorf("gpu: no NVIDIA card found")
+	}
+	logrus.Infof("gpu: %d NVIDIA card(s) detected", devices)
+
+	busIDs := getNvidiaBusIDs(nvidiaProcRoot)
+	b := &nvidiaBackend{}
+	for i := uint(0); i < devices; i++ {
+		c := nvidiaCard{index: int(i)}
+		if c.device, err = gonvml.DeviceHandleByIndex(i); err != nil {
+			return nil, fmt.Errorf("gpu: failed to watch on NVIDIA card %d: %s", i, err)
+		}
+		c.uuid, _ = c.device.UUID()
+		c.name, _ = c.device.Name()
+		if minor, err := c.device.MinorNumber(); err == nil {
+			c.busID = busIDs[minor]
+		}
+		b.cards = append(b.cards, c)
+	}
+	return b, nil
+}
+
+func (b *nvidiaBackend) stats() ([]Card, error) {
+	cards := make([]Card, 0, len(b.cards))
+	for _, nc := range b.cards {
+		c := Card{
+			Index: nc.index,
+			UUID:  nc.uuid,
+			BusID: nc.busID,
+			Name:  nc.name,
+		}
+		load, _, err := nc.device.UtilizationRates()
+		if err != nil {
+			return nil, fmt.Errorf("gpu: failed to get utilization of NVIDIA card %d: %s", nc.index, err)
+		}
+		total, used, err := nc.device.MemoryInfo()
+		if err != nil {
+			return nil, fmt.Errorf("gpu: failed to get memory info of NVIDIA card %d: %s", nc.index, err)
+		}
+		c.Load = float64(load)
+		c.Mem, c.MemTotal = used/1000000, total/1000000
+		// Sensors are not available on all cards (e.g.: passive cooling cards have no fan)
+		if temp, err := nc.device.Temperature(); err == nil {
+			c.Temp = float64(temp)
+		}
+		if fan, err := nc.device.FanSpeed(); err == nil {
+			c.Fan = float64(fan)
+		}
+		if power, err := nc.device.PowerUsage(); err == nil {
+			c.Power = float64(power) / 1000 // Milliwatts
+		}
+		// TODO: NVML binding doesn't expose clocks, power limit and throttle reasons yet
+		cards = append(cards, c)
+	}
+	return cards, nil
+}
+
+func (b *nvidiaBackend) close() error {
+	return gonvml.Shutdown()
+}
+
+func (b *nvidiaBackend) processes() (map[string][]Process, error) {
+	return nvidiaProcesses()
+}
+
+// getNvidiaBusIDs maps the device minor numbers to PCI bus IDs since NVML binding doesn't expose PCI info.
+// Each card has an information file in /proc/driver/nvidia/gpus/<bus ID>/ which contains its minor number.
+func getNvidiaBusIDs(root string) map[uint]string {
+	busIDs := make(map[uint]string)
+	dirs, _ := filepath.Glob(filepath.Join(root, "*"))
+	for _, dir := range dirs {
+		f, err := os.Open(filepath.Join(dir, "information"))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			kv := strings.SplitN(scanner.Text(), ":", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "Device Minor" {
+				if minor, err := strconv.ParseUint(strings.TrimSpace(kv[1]), 10, 32); err == nil {
+					busIDs[uint(minor)] = filepath.Base(dir)
+				}
+				break
+			}
+		}
+		f.Close()
+	}
+	return busIDs
+}
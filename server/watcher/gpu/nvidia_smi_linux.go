@@ -0,0 +1,213 @@
+// +build linux
+
+package gpu
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var nvidiaSMIPath = "nvidia-smi"
+
+// Queried fields, parseNvidiaSMILine depends on this order
+var nvidiaSMIFields = []string{
+	"index", "uuid", "pci.bus_id", "name", "utilization.gpu", "memory.used", "memory.total",
+	"temperature.gpu", "fan.speed", "power.draw", "power.limit", "clocks.gr", "clocks.max.gr", "clocks.mem",
+	"clocks_throttle_reasons.active",
+}
+
+// Active clocks throttle reasons bitmask, see nvmlClocksThrottleReasons in NVML API reference
+var nvidiaThrottleReasons = []struct {
+	mask   uint64
+	reason string
+}{
+	{0x1, "gpu_idle"},
+	{0x2, "applications_clocks_setting"},
+	{0x4, "sw_power_cap"},
+	{0x8, "hw_slowdown"},
+	{0x10, "sync_boost"},
+	{0x20, "sw_thermal_slowdown"},
+	{0x40, "hw_thermal_slowdown"},
+	{0x80, "hw_power_brake_slowdown"},
+	{0x100, "display_clock_setting"},
+}
+
+// nvidiaSMIBackend gets NVIDIA GPU statistics by running nvidia-smi in loop mode.
+// It's used when NVML binding is not usable (e.g.: libnvidia-ml doesn't match the driver).
+type nvidiaSMIBackend struct {
+	cmd    *exec.Cmd
+	done   chan struct{}
+	maxAge time.Duration // Cards which aren't sampled for longer are removed, e.g.: fell off the bus
+
+	mu       sync.Mutex
+	cards    map[int]Card      // Latest sample of each card
+	lastSeen map[int]time.Time // Time of latest sample of each card
+	err      error             // Set when nvidia-smi exited
+}
+
+func newNvidiaSMIBackend(interval time.Duration) (*nvidiaSMIBackend, error) {
+	path, err := exec.LookPath(nvidiaSMIPath)
+	if err != nil {
+		return nil, fmt.Errorf("gpu: nvidia-smi not found: %s", err)
+	}
+	ms := interval.Nanoseconds() / int64(time.Millisecond)
+	cmd := exec.Command(path, "--query-gpu="+strings.Join(nvidiaSMIFields, ","),
+		"--format=csv,noheader,nounits", "-lms", strconv.FormatInt(ms, 10))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("gpu: failed to run nvidia-smi: %s", err)
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("gpu: failed to run nvidia-smi: %s", err)
+	}
+	logrus.Infof("gpu: watching NVIDIA cards via %s", path)
+
+	b := &nvidiaSMIBackend{
+		cmd:      cmd,
+		done:     make(chan struct{}),
+		maxAge:   3 * interval,
+		cards:    make(map[int]Card),
+		lastSeen: make(map[int]time.Time),
+	}
+	go b.read(stdout)
+	return b, nil
+}
+
+// read parses nvidia-smi output until it exits.
+func (b *nvidiaSMIBackend) read(r io.Reader) {
+	defer close(b.done)
+	err := b.scan(r)
+	if err != nil {
+		// nvidia-smi would block on writing output which is no longer read
+		b.cmd.Process.Kill()
+	}
+	if e := b.cmd.Wait(); err == nil {
+		err = e
+	}
+	b.mu.Lock()
+	b.err = fmt.Errorf("gpu: nvidia-smi exited: %v", err)
+	b.mu.Unlock()
+}
+
+// scan stores the samples of nvidia-smi output, each sample prints one line per card.
+func (b *nvidiaSMIBackend) scan(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		c, err := parseNvidiaSMILine(scanner.Text())
+		if err != nil {
+			logrus.Debugf("gpu: %s", err)
+			continue
+		}
+		b.mu.Lock()
+		b.cards[c.Index] = c
+		b.lastSeen[c.Index] = time.Now()
+		b.mu.Unlock()
+	}
+	return scanner.Err()
+}
+
+// stats returns the latest samples of cards, errNoSample is returned until nvidia-smi prints the first sample.
+func (b *nvidiaSMIBackend) stats() ([]Card, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return nil, b.err
+	}
+	cards := make([]Card, 0, len(b.cards))
+	for i, c := range b.cards {
+		if time.Since(b.lastSeen[i]) > b.maxAge {
+			logrus.Warnf("gpu: NVIDIA card %d (%s) is not reported by nvidia-smi anymore", i, c.BusID)
+			delete(b.cards, i)
+			delete(b.lastSeen, i)
+			continue
+		}
+		cards = append(cards, c)
+	}
+	if len(cards) == 0 {
+		return nil, errNoSample
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].Index < cards[j].Index
+	})
+	return cards, nil
+}
+
+func (b *nvidiaSMIBackend) close() error {
+	select {
+	case <-b.done: // Already exited
+		return nil
+	default:
+	}
+	if err := b.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	<-b.done
+	return nil
+}
+
+func (b *nvidiaSMIBackend) processes() (map[string][]Process, error) {
+	return nvidiaProcesses()
+}
+
+// parseNvidiaSMILine parses one CSV line of nvidia-smi output (without header and units), e.g.:
+//
+//	0, GPU-5c0c2bd5-..., 00000000:01:00.0, GeForce GTX 1080, 12, 1024, 8192, 45, 30, 40.12, 180.00, 1607, 1911, 5005, 0x0000000000000001
+//
+// Unsupported fields are reported as [N/A] or [Not Supported] and left as zero.
+func parseNvidiaSMILine(line string) (Card, error) {
+	fields := strings.Split(line, ",")
+	if len(fields) != len(nvidiaSMIFields) {
+		return Card{}, fmt.Errorf("invalid nvidia-smi output: %q", line)
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	index, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return Card{}, fmt.Errorf("invalid nvidia-smi card index: %q", line)
+	}
+	c := Card{
+		Index: index,
+		UUID:  fields[1],
+		BusID: fields[2],
+		Name:  fields[3],
+	}
+	c.Load = parseSMIFloat(fields[4])
+	c.Mem = parseSMIUint(fields[5]) * 1048576 / 1000000 // MiB to MB
+	c.MemTotal = parseSMIUint(fields[6]) * 1048576 / 1000000
+	c.Temp = parseSMIFloat(fields[7])
+	c.Fan = parseSMIFloat(fields[8])
+	c.Power = parseSMIFloat(fields[9])
+	c.PowerLimit = parseSMIFloat(fields[10])
+	c.Clock = parseSMIUint(fields[11])
+	c.ClockMax = parseSMIUint(fields[12])
+	c.MemClock = parseSMIUint(fields[13])
+
+	c.Throttle = make([]string, 0)
+	if mask, err := strconv.ParseUint(strings.TrimPrefix(fields[14], "0x"), 16, 64); err == nil {
+		for _, tr := range nvidiaThrottleReasons {
+			if mask&tr.mask != 0 {
+				c.Throttle = append(c.Throttle, tr.reason)
+			}
+		}
+	}
+	return c, nil
+}
+
+func parseSMIFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+func parseSMIUint(s string) uint64 {
+	return uint64(parseSMIFloat(s))
+}
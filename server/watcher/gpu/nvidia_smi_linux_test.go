@@ -0,0 +1,139 @@
+// +build linux
+
+package gpu
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Output of nvidia-smi with the queried fields, captured on a desktop, a server (no fan) and a laptop card
+// which doesn't support fan, power and throttle reasons.
+const testNvidiaSMIQuery = "testdata/nvidia-smi/query.csv"
+
+func TestParseNvidiaSMILine(t *testing.T) {
+	f, err := os.Open(testNvidiaSMIQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want := []Card{
+		{Index: 0, UUID: "GPU-5c0c2bd5-1a4f-2e0d-8b3c-1f5e7a9d2c41", BusID: "00000000:01:00.0", Name: "NVIDIA GeForce RTX 3080", Metrics: Metrics{
+			Load: 35, Mem: 2147, MemTotal: 10737, Temp: 62, Fan: 45, Power: 120.5, PowerLimit: 320,
+			Clock: 1710, ClockMax: 2100, MemClock: 9501, Throttle: []string{"sw_power_cap"},
+		}},
+		{Index: 1, UUID: "GPU-0e9a7b3c-6d2f-4c1a-9f8e-3b7d5a1c0e62", BusID: "00000000:02:00.0", Name: "Tesla T4", Metrics: Metrics{
+			MemTotal: 16106, Temp: 38, Power: 27.84, PowerLimit: 70,
+			Clock: 300, ClockMax: 1590, MemClock: 405, Throttle: []string{"gpu_idle"},
+		}},
+		{Index: 2, UUID: "GPU-b71c4e2a-93d0-5f68-0a1b-7c2e4d6f8a13", BusID: "00000000:03:00.0", Name: "NVIDIA GeForce MX150", Metrics: Metrics{
+			Load: 5, Mem: 327, MemTotal: 2147, Temp: 48,
+			Clock: 1468, ClockMax: 1531, MemClock: 3004, Throttle: []string{},
+		}},
+	}
+	scanner := bufio.NewScanner(f)
+	i := 0
+	for ; scanner.Scan(); i++ {
+		c, err := parseNvidiaSMILine(scanner.Text())
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if i >= len(want) {
+			continue
+		}
+		checkCard(t, c, want[i])
+		if !reflect.DeepEqual(c.Throttle, want[i].Throttle) {
+			t.Errorf("%d: got throttle reasons %v, want %v", i, c.Throttle, want[i].Throttle)
+		}
+	}
+	if i != len(want) {
+		t.Errorf("got %d cards, want %d", i, len(want))
+	}
+}
+
+func TestParseNvidiaSMILineInvalid(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"No devices were found",
+		"0, GPU-5c0c2bd5, 00000000:01:00.0, NVIDIA GeForce RTX 3080, 35", // Missing fields
+		"[N/A], GPU-5c0c2bd5, 00000000:01:00.0, NVIDIA GeForce RTX 3080, 35, 2048, 10240, 62, 45, 120.50, 320.00, 1710, 2100, 9501, 0x0",
+	} {
+		if c, err := parseNvidiaSMILine(line); err == nil {
+			t.Errorf("%q: got %+v, want error", line, c)
+		}
+	}
+}
+
+func TestNvidiaSMISamples(t *testing.T) {
+	b := &nvidiaSMIBackend{
+		maxAge:   time.Minute,
+		cards:    make(map[int]Card),
+		lastSeen: make(map[int]time.Time),
+	}
+	if _, err := b.stats(); err != errNoSample {
+		t.Fatalf("got error %v before first sample, want %v", err, errNoSample)
+	}
+
+	f, err := os.Open(testNvidiaSMIQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := b.scan(f); err != nil {
+		t.Fatal(err)
+	}
+	cards, err := b.stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 3 || cards[0].Index != 0 || cards[1].Index != 1 || cards[2].Index != 2 {
+		t.Fatalf("got %+v, want 3 cards sorted by index", cards)
+	}
+
+	// Card 1 fell off the bus
+	b.lastSeen[1] = time.Now().Add(-2 * time.Minute)
+	if cards, err = b.stats(); err != nil || len(cards) != 2 || cards[0].Index != 0 || cards[1].Index != 2 {
+		t.Fatalf("got %+v (%v), want cards 0 and 2", cards, err)
+	}
+	if _, ok := b.cards[1]; ok {
+		t.Error("card 1 is not removed")
+	}
+
+	b.lastSeen[0], b.lastSeen[2] = time.Time{}, time.Time{}
+	if _, err := b.stats(); err != errNoSample {
+		t.Errorf("got error %v without recent sample, want %v", err, errNoSample)
+	}
+}
+
+func TestNvidiaSMIReadLongLine(t *testing.T) {
+	// Line is longer than the scanner buffer and the command never exits by itself
+	cmd := exec.Command("sh", "-c", "head -c 100000 /dev/zero | tr '\\0' a; exec sleep 60")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("failed to run sh: %s", err)
+	}
+	b := &nvidiaSMIBackend{
+		cmd:      cmd,
+		done:     make(chan struct{}),
+		maxAge:   time.Minute,
+		cards:    make(map[int]Card),
+		lastSeen: make(map[int]time.Time),
+	}
+	go b.read(stdout)
+	select {
+	case <-b.done:
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("nvidia-smi is not killed on read error")
+	}
+	if _, err := b.stats(); err == nil {
+		t.Error("expected error after nvidia-smi exited")
+	}
+}
@@ -0,0 +1,221 @@
+// +build linux
+
+package gpu
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lnquy/nights-watch/server/util"
+	"github.com/sirupsen/logrus"
+)
+
+var procRoot = "/proc"
+
+// newProcess returns a process using GPU with its owner and name resolved from procfs.
+func newProcess(pid int, name, typ string, mem uint64) Process {
+	p := Process{
+		PID:  pid,
+		Name: name,
+		Mem:  mem,
+		Type: typ,
+	}
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	if p.Name == "" {
+		p.Name = util.ReadString(filepath.Join(dir, "comm"))
+	}
+	if uid := util.ReadUID(filepath.Join(dir, "status")); uid != "" {
+		p.User = uid
+		if u, err := user.LookupId(uid); err == nil {
+			p.User = u.Username
+		}
+	}
+	return p
+}
+
+// drmProcesses lists processes which opened DRM devices of the driver, grouped by card PCI bus ID.
+// DRM clients expose their usage in /proc/<pid>/fdinfo/<fd>, e.g.:
+//
+//	drm-driver:      amdgpu
+//	drm-pdev:        0000:03:00.0
+//	drm-client-id:   42
+//	drm-memory-vram: 10240 KiB
+//
+// Memory of the keys matched the memory prefixes is summed as the process GPU memory.
+// Only processes of the same user are visible unless running as root.
+func drmProcesses(driver string, memPrefixes []string) (map[string][]Process, error) {
+	pids, err := filepath.Glob(filepath.Join(procRoot, "[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	procs := make(map[string][]Process)
+	for _, dir := range pids {
+		pid, err := strconv.Atoi(filepath.Base(dir))
+		if err != nil {
+			continue
+		}
+		fds, _ := filepath.Glob(filepath.Join(dir, "fdinfo", "*"))
+		clients := make(map[string]bool) // A DRM client can be shared by multiple file descriptors
+		mems := make(map[string]uint64)  // Memory per bus ID
+		for _, fd := range fds {
+			f, err := os.Open(fd)
+			if err != nil {
+				continue
+			}
+			info := parseDRMFdinfo(f)
+			f.Close()
+			if info["drm-driver"] != driver || info["drm-pdev"] == "" || clients[info["drm-client-id"]] {
+				continue
+			}
+			clients[info["drm-client-id"]] = true
+			var mem uint64
+			for k, v := range info {
+				for _, prefix := range memPrefixes {
+					if strings.HasPrefix(k, prefix) {
+						mem += parseMemSize(v)
+					}
+				}
+			}
+			mems[info["drm-pdev"]] += mem
+		}
+		for busID, mem := range mems {
+			procs[busID] = append(procs[busID], newProcess(pid, "", "graphics", mem/1000000))
+		}
+	}
+	return procs, nil
+}
+
+// parseDRMFdinfo returns the drm-* keys of a file descriptor info.
+func parseDRMFdinfo(r io.Reader) map[string]string {
+	info := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], "drm-") {
+			continue
+		}
+		info[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return info
+}
+
+// parseMemSize parses memory sizes in fdinfo (e.g.: 1024 KiB) to bytes.
+func parseMemSize(s string) uint64 {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+	v, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	if len(fields) > 1 {
+		switch fields[1] {
+		case "KiB":
+			v *= 1 << 10
+		case "MiB":
+			v *= 1 << 20
+		case "GiB":
+			v *= 1 << 30
+		}
+	}
+	return v
+}
+
+// nvidiaProcesses lists processes of NVIDIA cards via nvidia-smi since NVML binding doesn't expose them.
+// Compute processes are listed with their memory usage, then graphics processes (e.g.: Xorg, games)
+// are listed by the process monitor if it's supported by the cards.
+func nvidiaProcesses() (map[string][]Process, error) {
+	out, err := exec.Command(nvidiaSMIPath, "--query-compute-apps=gpu_bus_id,pid,process_name,used_memory",
+		"--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return nil, err
+	}
+	procs := parseNvidiaSMIProcesses(bytes.NewReader(out))
+	if out, err = exec.Command(nvidiaSMIPath, "pmon", "-c", "1", "-s", "m").Output(); err != nil {
+		logrus.Debugf("gpu: failed to list NVIDIA graphics processes: %s", err)
+		return procs, nil
+	}
+	for index, ps := range parseNvidiaSMIPmon(bytes.NewReader(out)) {
+		procs[index] = append(procs[index], ps...)
+	}
+	return procs, nil
+}
+
+// parseNvidiaSMIProcesses parses nvidia-smi compute apps output (without header and units), e.g.:
+//
+//	00000000:01:00.0, 1234, /usr/bin/python3, 2048
+func parseNvidiaSMIProcesses(r io.Reader) map[string][]Process {
+	procs := make(map[string][]Process)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ",")
+		if len(fields) != 4 {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil {
+			continue
+		}
+		busID, name := strings.TrimSpace(fields[0]), filepath.Base(strings.TrimSpace(fields[2]))
+		mem := parseSMIUint(strings.TrimSpace(fields[3])) * 1048576 / 1000000 // MiB to MB
+		procs[busID] = append(procs[busID], newProcess(pid, name, "compute", mem))
+	}
+	return procs
+}
+
+// parseNvidiaSMIPmon parses graphics processes of nvidia-smi process monitor output, grouped by card index, e.g.:
+//
+//	# gpu         pid  type    fb   ccpm  command
+//	# Idx           #   C/G    MB     MB  name
+//	    0        1843     G    52      0  Xorg
+//	    0        9120     C  2046      0  python3
+//	    1           -     -     -      -  -
+//
+// Columns are found by the header since they differ between driver versions.
+// Compute processes (C and C+G) are skipped as they are listed with compute apps already.
+func parseNvidiaSMIPmon(r io.Reader) map[string][]Process {
+	procs := make(map[string][]Process)
+	cols := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			if len(cols) == 0 { // Column names, the second header line is units
+				for i, name := range strings.Fields(line[1:]) {
+					cols[name] = i
+				}
+			}
+			continue
+		}
+		gpuCol, ok1 := cols["gpu"]
+		pidCol, ok2 := cols["pid"]
+		typeCol, ok3 := cols["type"]
+		cmdCol, ok4 := cols["command"]
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) <= cmdCol || fields[typeCol] != "G" {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[pidCol])
+		if err != nil {
+			continue
+		}
+		var mem uint64
+		if fbCol, ok := cols["fb"]; ok {
+			mem = parseSMIUint(fields[fbCol]) * 1048576 / 1000000 // MiB to MB
+		}
+		name := strings.Join(fields[cmdCol:], " ")
+		procs[fields[gpuCol]] = append(procs[fields[gpuCol]], newProcess(pid, name, "graphics", mem))
+	}
+	return procs
+}
@@ -0,0 +1,143 @@
+// +build linux
+
+package gpu
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Fixtures of procfs in testdata/proc: Xorg (1200) uses an AMD card via 2 file descriptors of the same DRM client,
+// firefox (3400) uses the AMD card and an Intel card.
+const testProcRoot = "testdata/proc"
+
+func TestDRMProcesses(t *testing.T) {
+	defer func(root string) { procRoot = root }(procRoot)
+	procRoot = testProcRoot
+
+	tests := []struct {
+		driver      string
+		memPrefixes []string
+		want        map[string][]Process
+	}{
+		{"amdgpu", []string{"drm-memory-vram"}, map[string][]Process{
+			"0000:03:00.0": {{PID: 1200, Name: "Xorg", Mem: 10, Type: "graphics"}, {PID: 3400, Name: "firefox", Mem: 2, Type: "graphics"}},
+		}},
+		{"i915", []string{"drm-resident-"}, map[string][]Process{
+			"0000:00:02.0": {{PID: 3400, Name: "firefox", Mem: 9, Type: "graphics"}},
+		}},
+		{"nouveau", nil, map[string][]Process{}},
+	}
+	for _, tt := range tests {
+		procs, err := drmProcesses(tt.driver, tt.memPrefixes)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.driver, err)
+		}
+		if got := withoutUsers(procs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.driver, got, tt.want)
+		}
+	}
+}
+
+func TestParseDRMFdinfo(t *testing.T) {
+	f, err := os.Open("testdata/proc/3400/fdinfo/13")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want := map[string]string{
+		"drm-driver":                  "i915",
+		"drm-pdev":                    "0000:00:02.0",
+		"drm-client-id":               "11",
+		"drm-total-system0":           "16384 KiB",
+		"drm-resident-system0":        "8192 KiB",
+		"drm-resident-stolen-system0": "1 MiB",
+		"drm-engine-render":           "9981223 ns",
+	}
+	if got := parseDRMFdinfo(f); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Not a DRM file descriptor
+	if got := parseDRMFdinfo(strings.NewReader("pos:\t0\nflags:\t02\nmnt_id:\t25\n")); len(got) != 0 {
+		t.Errorf("got %v, want empty", got)
+	}
+}
+
+func TestParseMemSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want uint64
+	}{
+		{"10240 KiB", 10485760},
+		{"2 MiB", 2097152},
+		{"1 GiB", 1073741824},
+		{"512", 512},
+		{"0 KiB", 0},
+		{"", 0},
+		{"-1 KiB", 0},
+		{"lots", 0},
+	}
+	for _, tt := range tests {
+		if got := parseMemSize(tt.s); got != tt.want {
+			t.Errorf("%q: got %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseNvidiaSMIProcesses(t *testing.T) {
+	f, err := os.Open("testdata/nvidia-smi/compute-apps.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	procs := parseNvidiaSMIProcesses(f)
+	want := map[string][]Process{
+		"00000000:01:00.0": {{PID: 9120, Name: "python3", Mem: 2145, Type: "compute"}},
+		"00000000:02:00.0": {{PID: 9311, Name: "trainer", Type: "compute"}}, // Memory is not available in MIG mode
+	}
+	if got := withoutUsers(procs); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseNvidiaSMIPmon(t *testing.T) {
+	tests := []struct {
+		file string
+		want map[string][]Process
+	}{
+		{"testdata/nvidia-smi/pmon.txt", map[string][]Process{
+			"0": {{PID: 1843, Name: "Xorg", Mem: 54, Type: "graphics"}, {PID: 2210, Name: "gnome-shell", Mem: 125, Type: "graphics"}},
+		}},
+		// Older drivers without ccpm column and unknown memory
+		{"testdata/nvidia-smi/pmon-old.txt", map[string][]Process{
+			"0": {{PID: 1843, Name: "Xorg", Type: "graphics"}, {PID: 5120, Name: "Web Content", Type: "graphics"}},
+		}},
+	}
+	for _, tt := range tests {
+		f, err := os.Open(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		procs := parseNvidiaSMIPmon(f)
+		f.Close()
+		if got := withoutUsers(procs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.file, got, tt.want)
+		}
+	}
+	// No header
+	if got := parseNvidiaSMIPmon(strings.NewReader("    0        1843     G    52    Xorg\n")); len(got) != 0 {
+		t.Errorf("got %+v without header, want empty", got)
+	}
+}
+
+// withoutUsers clears the process users which depend on the system running tests.
+func withoutUsers(procs map[string][]Process) map[string][]Process {
+	for _, ps := range procs {
+		for i := range ps {
+			ps[i].User = ""
+		}
+	}
+	return procs
+}
@@ -0,0 +1,118 @@
+package kernel
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Event types
+const (
+	OOM        = "oom"
+	Hardware   = "hardware"   // Machine check exceptions and EDAC memory errors
+	Filesystem = "filesystem" // ext2/3/4, XFS and Btrfs errors
+	Segfault   = "segfault"
+)
+
+const maxRecent = 20
+
+var (
+	oomRegex      = regexp.MustCompile(`Killed process (\d+) \(([^)]*)\)`)
+	segfaultRegex = regexp.MustCompile(`^(.+)\[(\d+)\]: segfault at`)
+	hardwareRegex = regexp.MustCompile(`(?i)\[Hardware Error\]|machine check|^EDAC `)
+	fsRegex       = regexp.MustCompile(`(?i)^(EXT[234]-fs|XFS|BTRFS)\b.*(error|corrupt)`)
+)
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	// Stats holds the kernel events detected since the last sample and the recent ones.
+	Stats struct {
+		OOMKills uint64  `json:"oomKills"` // Total OOM kills since boot
+		Events   []Event `json:"events"`   // New events since the last sample
+		Recent   []Event `json:"recent"`   // Latest events, the newest first
+	}
+
+	Event struct {
+		Time    time.Time `json:"time"`
+		Type    string    `json:"type"`
+		Process string    `json:"process,omitempty"` // Victim process name if known
+		PID     int       `json:"pid,omitempty"`
+		Message string    `json:"message"`
+	}
+
+	watcher struct{}
+)
+
+func NewWatcher() Watcher {
+	return &watcher{}
+}
+
+// GetStats reads kernel log from /dev/kmsg for events and the OOM kills counter from /proc/vmstat.
+// If kernel log is not readable (requires CAP_SYSLOG when dmesg_restrict is set),
+// only OOM kills are reported without victim process.
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	go func() {
+		logrus.Infof("watcher: KERNEL watcher started")
+		c := newCollector()
+		recent := make([]Event, 0, maxRecent)
+		for {
+			select {
+			case <-ticker.C:
+				stats, err := c.collect()
+				if err != nil {
+					logrus.Error(err)
+					continue
+				}
+				for _, e := range stats.Events {
+					recent = append([]Event{e}, recent...)
+				}
+				if len(recent) > maxRecent {
+					recent = recent[:maxRecent]
+				}
+				stats.Recent = append([]Event{}, recent...)
+				statsChan <- stats
+			case <-ctx.Done():
+				c.close()
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: KERNEL watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
+
+// parseEvent detects the event from a kernel log message, returns nil if the message is not an event.
+func parseEvent(msg string, t time.Time) *Event {
+	e := &Event{Time: t, Message: msg}
+	switch {
+	case strings.Contains(msg, "Killed process"):
+		m := oomRegex.FindStringSubmatch(msg)
+		if m == nil {
+			return nil
+		}
+		e.Type, e.Process = OOM, m[2]
+		e.PID, _ = strconv.Atoi(m[1])
+	case segfaultRegex.MatchString(msg):
+		m := segfaultRegex.FindStringSubmatch(msg)
+		e.Type, e.Process = Segfault, m[1]
+		e.PID, _ = strconv.Atoi(m[2])
+	case hardwareRegex.MatchString(msg):
+		e.Type = Hardware
+	case fsRegex.MatchString(msg):
+		e.Type = Filesystem
+	default:
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,165 @@
+// +build linux
+
+package kernel
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	kmsgPath   = "/dev/kmsg"
+	vmstatFile = "/proc/vmstat"
+)
+
+type collector struct {
+	kmsg    *os.File
+	events  chan Event
+	lastOOM uint64
+	started bool
+	pending uint64 // OOM kills counted in the last sample which were not logged yet
+}
+
+func newCollector() *collector {
+	c := &collector{events: make(chan Event, 100)}
+	f, err := os.Open(kmsgPath)
+	if err != nil {
+		logrus.Warnf("kernel: failed to read kernel log, only OOM kills are watched: %s", err)
+		return c
+	}
+	// Skip messages logged before watcher started
+	if _, err = f.Seek(0, io.SeekEnd); err != nil {
+		logrus.Warnf("kernel: failed to seek kernel log: %s", err)
+	}
+	c.kmsg = f
+	go c.read()
+	return c
+}
+
+// read parses kernel log records until kmsg is closed. Each read returns exactly one record.
+func (c *collector) read() {
+	buf := make([]byte, 8192)
+	for {
+		n, err := c.kmsg.Read(buf)
+		if err != nil {
+			if pe, ok := err.(*os.PathError); ok && pe.Err == syscall.EPIPE {
+				continue // Some records were overwritten before being read
+			}
+			return
+		}
+		e := parseEvent(parseRecord(string(buf[:n])), time.Now())
+		if e == nil {
+			continue
+		}
+		select {
+		case c.events <- *e:
+		default:
+			logrus.Debugf("kernel: event dropped: %s", e.Message)
+		}
+	}
+}
+
+func (c *collector) collect() (*Stats, error) {
+	stats := &Stats{Events: make([]Event, 0)}
+	oomEvents := uint64(0)
+	for drained := false; !drained; {
+		select {
+		case e := <-c.events:
+			stats.Events = append(stats.Events, e)
+			if e.Type == OOM {
+				oomEvents++
+			}
+		default:
+			drained = true
+		}
+	}
+
+	oom, err := getOOMKills()
+	if err != nil {
+		return nil, fmt.Errorf("kernel: failed to get OOM kills: %s", err)
+	}
+	stats.OOMKills = oom
+	if c.started && oom >= c.lastOOM {
+		// OOM kills which were not seen in kernel log (e.g.: kernel log is not readable)
+		for i := uint64(0); i < c.unloggedOOMs(oom-c.lastOOM, oomEvents); i++ {
+			stats.Events = append(stats.Events, Event{
+				Time:    time.Now(),
+				Type:    OOM,
+				Message: "Out of memory: process killed",
+			})
+		}
+	}
+	c.lastOOM, c.started = oom, true
+	return stats, nil
+}
+
+// unloggedOOMs returns the number of OOM kills which are counted but not logged by kernel.
+// The counter is increased before the kill is logged so the log may only be read in the next sample,
+// kills are considered as unlogged if they are still not logged one sample later.
+func (c *collector) unloggedOOMs(kills, logged uint64) uint64 {
+	if c.kmsg == nil {
+		return kills
+	}
+	// Logged kills match the kills pending from the last sample first
+	matched := minUint(logged, c.pending)
+	unlogged := c.pending - matched
+	logged -= matched
+	c.pending = kills - minUint(logged, kills)
+	return unlogged
+}
+
+func (c *collector) close() {
+	if c.kmsg != nil {
+		c.kmsg.Close()
+	}
+}
+
+// parseRecord returns the message of a kernel log record, e.g.:
+//
+//	6,1234,5140900,-;Out of memory: Killed process 4321 (java) total-vm:...
+//	 SUBSYSTEM=...
+func parseRecord(record string) string {
+	if i := strings.IndexByte(record, '\n'); i >= 0 {
+		record = record[:i] // Drop continuation lines
+	}
+	if i := strings.IndexByte(record, ';'); i >= 0 {
+		record = record[i+1:]
+	}
+	return strings.TrimSpace(record)
+}
+
+// getOOMKills returns the total number of OOM kills since boot (kernel 4.13+).
+func getOOMKills() (uint64, error) {
+	f, err := os.Open(vmstatFile)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("oom_kill not found in %s", vmstatFile)
+}
+
+func minUint(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,94 @@
+// +build linux
+
+package kernel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectOOMKills(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kernel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(fp string) { vmstatFile = fp }(vmstatFile)
+	vmstatFile = filepath.Join(dir, "vmstat")
+	fixture, err := ioutil.ReadFile("testdata/vmstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Kernel log is only used to check whether it's readable
+	kmsg, err := os.Open("testdata/vmstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kmsg.Close()
+
+	tests := []struct {
+		name     string
+		kmsg     *os.File
+		samples  []string // oom_kill counter of each sample
+		logged   []int    // OOM kills logged before each sample
+		reported []int    // OOM events reported by each sample, both logged and unlogged
+	}{
+		{"logged in the same sample", kmsg, []string{"3", "5"}, []int{0, 2}, []int{0, 2}},
+		{"logged in the next sample", kmsg, []string{"3", "4", "4", "4"}, []int{0, 0, 1, 0}, []int{0, 0, 1, 0}},
+		{"never logged", kmsg, []string{"3", "4", "5", "5", "5"}, []int{0, 0, 0, 0, 0}, []int{0, 0, 1, 1, 0}},
+		{"partly logged", kmsg, []string{"3", "6", "6"}, []int{0, 1, 1}, []int{0, 1, 2}},
+		{"logged before watcher started", kmsg, []string{"3", "3"}, []int{1, 1}, []int{1, 1}},
+		{"kernel log is not readable", nil, []string{"3", "5", "5"}, []int{0, 0, 0}, []int{0, 2, 0}},
+	}
+	for _, tt := range tests {
+		c := &collector{kmsg: tt.kmsg, events: make(chan Event, 100)}
+		for i, oom := range tt.samples {
+			content := strings.Replace(string(fixture), "oom_kill 3", "oom_kill "+oom, 1)
+			if err := ioutil.WriteFile(vmstatFile, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			for j := 0; j < tt.logged[i]; j++ {
+				c.events <- Event{Time: time.Now(), Type: OOM, Process: "java", PID: 4321}
+			}
+			s, err := c.collect()
+			if err != nil {
+				t.Fatalf("%s: %s", tt.name, err)
+			}
+			if len(s.Events) != tt.reported[i] {
+				t.Errorf("%s: sample %d: got %d events, want %d", tt.name, i, len(s.Events), tt.reported[i])
+			}
+		}
+	}
+}
+
+func TestGetOOMKills(t *testing.T) {
+	defer func(fp string) { vmstatFile = fp }(vmstatFile)
+	vmstatFile = "testdata/vmstat"
+	if oom, err := getOOMKills(); err != nil || oom != 3 {
+		t.Errorf("got %d (%v), want 3", oom, err)
+	}
+	// Kernel older than 4.13
+	vmstatFile = "testdata/missing"
+	if _, err := getOOMKills(); err == nil {
+		t.Error("expected error without vmstat")
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	tests := []struct {
+		record, want string
+	}{
+		{"6,1234,5140900,-;Out of memory: Killed process 4321 (java) total-vm:8123456kB\n SUBSYSTEM=memory\n", "Out of memory: Killed process 4321 (java) total-vm:8123456kB"},
+		{"4,99,1,-;EXT4-fs error (device sda1): bad block\n", "EXT4-fs error (device sda1): bad block"},
+		{"no prefix", "no prefix"},
+	}
+	for _, tt := range tests {
+		if got := parseRecord(tt.record); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.record, got, tt.want)
+		}
+	}
+}
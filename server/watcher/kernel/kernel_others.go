@@ -0,0 +1,17 @@
+// +build !linux
+
+package kernel
+
+import "fmt"
+
+type collector struct{}
+
+func newCollector() *collector {
+	return &collector{}
+}
+
+func (c *collector) collect() (*Stats, error) {
+	return nil, fmt.Errorf("kernel: kernel events are only available on Linux")
+}
+
+func (c *collector) close() {}
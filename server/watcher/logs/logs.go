@@ -0,0 +1,147 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/lnquy/nights-watch/server/util"
+	"github.com/sirupsen/logrus"
+)
+
+// Matches are counted in a sliding window of this duration
+const window = time.Minute
+
+// Matched lines longer than this are truncated
+const maxLineLength = 200
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	Pattern struct {
+		Name    string
+		File    string
+		Pattern string // Regular expression
+	}
+
+	Stats struct {
+		Patterns []Match `json:"patterns"`
+	}
+
+	Match struct {
+		Name      string    `json:"name"`
+		File      string    `json:"file"`
+		Count     uint64    `json:"count"`     // Matched lines since the last sample
+		PerMinute uint64    `json:"perMinute"` // Matched lines in the last minute
+		Total     uint64    `json:"total"`     // Matched lines since the watcher started
+		LastLine  string    `json:"lastLine,omitempty"`
+		LastTime  time.Time `json:"lastTime"`
+		Error     string    `json:"error,omitempty"`
+	}
+
+	// matcher counts the lines matched a pattern.
+	matcher struct {
+		Match
+		regex   *regexp.Regexp
+		samples []sample // Counts in the last window
+	}
+
+	sample struct {
+		time  time.Time
+		count uint64
+	}
+
+	watcher struct {
+		patterns []Pattern
+	}
+)
+
+func NewWatcher(patterns []Pattern) Watcher {
+	return &watcher{patterns: patterns}
+}
+
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	go func() {
+		logrus.Infof("watcher: LOGS watcher started")
+		tailers := make(map[string]*tailer)
+		matchers := make([]*matcher, 0, len(w.patterns))
+		for _, p := range w.patterns {
+			m := &matcher{Match: Match{Name: p.Name, File: p.File}}
+			var err error
+			if m.regex, err = regexp.Compile(p.Pattern); err != nil {
+				m.Error = fmt.Sprintf("invalid pattern: %s", err)
+				logrus.Errorf("logs: %s: %s", p.Name, m.Error)
+			}
+			if tailers[p.File] == nil {
+				tailers[p.File] = newTailer(p.File)
+			}
+			matchers = append(matchers, m)
+		}
+		for {
+			select {
+			case <-ticker.C:
+				statsChan <- collect(tailers, matchers, time.Now())
+			case <-ctx.Done():
+				for _, t := range tailers {
+					t.close()
+				}
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: LOGS watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
+
+// collect reads new lines of all files and matches them against the patterns of each file.
+func collect(tailers map[string]*tailer, matchers []*matcher, now time.Time) *Stats {
+	lines := make(map[string][]string, len(tailers))
+	errs := make(map[string]error)
+	for file, t := range tailers {
+		lines[file], errs[file] = t.readLines()
+		if errs[file] != nil {
+			logrus.Debugf("logs: %s", errs[file])
+		}
+	}
+
+	stats := &Stats{Patterns: make([]Match, 0, len(matchers))}
+	for _, m := range matchers {
+		if m.regex != nil {
+			m.Error = ""
+			if errs[m.File] != nil {
+				m.Error = errs[m.File].Error()
+			}
+			m.match(lines[m.File], now)
+		}
+		stats.Patterns = append(stats.Patterns, m.Match)
+	}
+	return stats
+}
+
+func (m *matcher) match(lines []string, now time.Time) {
+	m.Count = 0
+	for _, l := range lines {
+		if !m.regex.MatchString(l) {
+			continue
+		}
+		m.Count++
+		m.LastLine, m.LastTime = util.Truncate(l, maxLineLength), now
+	}
+	m.Total += m.Count
+
+	m.samples = append(m.samples, sample{time: now, count: m.Count})
+	for len(m.samples) > 0 && now.Sub(m.samples[0].time) >= window {
+		m.samples = m.samples[1:]
+	}
+	m.PerMinute = 0
+	for _, s := range m.samples {
+		m.PerMinute += s.count
+	}
+}
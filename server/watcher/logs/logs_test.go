@@ -0,0 +1,76 @@
+package logs
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchWindow(t *testing.T) {
+	m := &matcher{regex: regexp.MustCompile(`ERROR`)}
+	start := time.Now()
+	tests := []struct {
+		elapsed   time.Duration
+		lines     []string
+		count     uint64
+		perMinute uint64
+		total     uint64
+	}{
+		{0, []string{"ERROR a", "INFO b", "ERROR c"}, 2, 2, 2},
+		{20 * time.Second, []string{"ERROR d"}, 1, 3, 3},
+		{40 * time.Second, nil, 0, 3, 3},
+		{59 * time.Second, []string{"ERROR e"}, 1, 4, 4},
+		{60 * time.Second, nil, 0, 2, 4}, // First sample is out of the window
+		{80 * time.Second, nil, 0, 1, 4},
+		{3 * time.Minute, []string{"ERROR f", "ERROR g"}, 2, 2, 6},
+	}
+	for i, tt := range tests {
+		m.match(tt.lines, start.Add(tt.elapsed))
+		if m.Count != tt.count || m.PerMinute != tt.perMinute || m.Total != tt.total {
+			t.Errorf("%d: got count %d, per minute %d, total %d, want %d, %d, %d",
+				i, m.Count, m.PerMinute, m.Total, tt.count, tt.perMinute, tt.total)
+		}
+	}
+	if m.LastLine != "ERROR g" || !m.LastTime.Equal(start.Add(3*time.Minute)) {
+		t.Errorf("got last line %q at %s", m.LastLine, m.LastTime)
+	}
+
+	m.match([]string{"ERROR " + strings.Repeat("x", 2*maxLineLength)}, start.Add(4*time.Minute))
+	if len(m.LastLine) != maxLineLength {
+		t.Errorf("got last line of %d bytes, want %d", len(m.LastLine), maxLineLength)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "app.log")
+	appendFile(t, fp, "")
+
+	tailers := map[string]*tailer{fp: newTailer(fp), filepath.Join(dir, "missing.log"): newTailer(filepath.Join(dir, "missing.log"))}
+	defer func() {
+		for _, tl := range tailers {
+			tl.close()
+		}
+	}()
+	matchers := []*matcher{
+		{Match: Match{Name: "errors", File: fp}, regex: regexp.MustCompile(`ERROR`)},
+		{Match: Match{Name: "timeouts", File: fp}, regex: regexp.MustCompile(`timed? ?out`)},
+		{Match: Match{Name: "missing", File: filepath.Join(dir, "missing.log")}, regex: regexp.MustCompile(`.`)},
+		{Match: Match{Name: "invalid", File: fp, Error: "invalid pattern"}},
+	}
+	appendFile(t, fp, "ERROR request timed out\nINFO ok\nERROR disk full\n")
+	stats := collect(tailers, matchers, time.Now())
+	want := []struct {
+		count uint64
+		err   bool
+	}{{2, false}, {1, false}, {0, true}, {0, true}}
+	for i, p := range stats.Patterns {
+		if p.Count != want[i].count || (p.Error != "") != want[i].err {
+			t.Errorf("%s: got count %d and error %q, want %d (error: %v)", p.Name, p.Count, p.Error, want[i].count, want[i].err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package logs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/lnquy/nights-watch/server/util"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxReadSize = 1 << 20  // Bytes read from a file per poll, the rest is read by the next polls
+	maxLineSize = 64 << 10 // Longer lines are truncated
+)
+
+// tailer follows a file by polling, it reopens the file when it's rotated and reads from the beginning when it's truncated.
+type tailer struct {
+	path    string
+	file    *os.File
+	info    os.FileInfo
+	partial string // Last line which is not terminated yet
+}
+
+// newTailer opens the file and skips its existing content. If the file doesn't exist yet, it'll be read
+// from the beginning when created.
+func newTailer(path string) *tailer {
+	t := &tailer{path: path}
+	if err := t.open(true); err != nil {
+		logrus.Warnf("logs: %s, waiting for it to be created", err)
+	}
+	return t
+}
+
+func (t *tailer) open(fromEnd bool) error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %s", t.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat %s: %s", t.path, err)
+	}
+	if fromEnd {
+		if _, err = f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to seek %s: %s", t.path, err)
+		}
+	}
+	t.file, t.info, t.partial = f, info, ""
+	return nil
+}
+
+// readLines returns the lines appended since the last read.
+func (t *tailer) readLines() ([]string, error) {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		if t.file == nil {
+			return nil, fmt.Errorf("failed to stat %s: %s", t.path, err)
+		}
+		return t.read() // Rotated but the new file is not created yet, keep reading the old one
+	}
+	if t.file == nil {
+		if err = t.open(false); err != nil {
+			return nil, err
+		}
+		return t.read()
+	}
+
+	var lines []string
+	if !os.SameFile(info, t.info) { // Rotated: read the rest of the old file then follow the new one
+		lines, _ = t.read()
+		if t.partial != "" {
+			lines = append(lines, t.partial)
+		}
+		if skipped := t.unread(); skipped > 0 {
+			logrus.Warnf("logs: skipped last %d bytes of rotated %s", skipped, t.path)
+		}
+		t.close()
+		if err = t.open(false); err != nil {
+			return lines, err
+		}
+	} else if offset, err := t.file.Seek(0, io.SeekCurrent); err == nil && info.Size() < offset { // Truncated
+		if _, err = t.file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to seek %s: %s", t.path, err)
+		}
+		t.partial = ""
+	}
+	more, err := t.read()
+	return append(lines, more...), err
+}
+
+// read returns the complete lines of at most maxReadSize bytes from the current offset.
+func (t *tailer) read() ([]string, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(t.file, maxReadSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", t.path, err)
+	}
+	if len(b) == 0 {
+		return nil, nil
+	}
+	lines := strings.Split(t.partial+string(b), "\n")
+	// The rest of a too long line is dropped while it's not terminated
+	t.partial = util.Truncate(lines[len(lines)-1], maxLineSize)
+	lines = lines[:len(lines)-1]
+	for i := range lines {
+		lines[i] = util.Truncate(strings.TrimSuffix(lines[i], "\r"), maxLineSize)
+	}
+	return lines, nil
+}
+
+// unread returns the number of bytes after the current offset.
+func (t *tailer) unread() int64 {
+	info, err := t.file.Stat()
+	if err != nil {
+		return 0
+	}
+	offset, err := t.file.Seek(0, io.SeekCurrent)
+	if err != nil || offset >= info.Size() {
+		return 0
+	}
+	return info.Size() - offset
+}
+
+func (t *tailer) close() {
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
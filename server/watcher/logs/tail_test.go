@@ -0,0 +1,158 @@
+package logs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func appendFile(t *testing.T, fp, s string) {
+	f, err := os.OpenFile(fp, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkLines(t *testing.T, step string, tl *tailer, want []string) {
+	got, err := tl.readLines()
+	if err != nil {
+		t.Errorf("%s: %s", step, err)
+	}
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got %q, want %q", step, got, want)
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestTailAppend(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "app.log")
+	appendFile(t, fp, "existing line\n")
+
+	tl := newTailer(fp)
+	defer tl.close()
+	checkLines(t, "existing content", tl, nil)
+	appendFile(t, fp, "first\r\nsecond\nthi")
+	checkLines(t, "append", tl, []string{"first", "second"})
+	checkLines(t, "no change", tl, nil)
+	appendFile(t, fp, "rd\n")
+	checkLines(t, "partial line", tl, []string{"third"})
+}
+
+func TestTailRenameRotation(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "app.log")
+	appendFile(t, fp, "")
+
+	tl := newTailer(fp)
+	defer tl.close()
+	appendFile(t, fp, "before\n")
+	checkLines(t, "before rotation", tl, []string{"before"})
+
+	appendFile(t, fp, "old 1\nold 2")
+	if err := os.Rename(fp, fp+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, fp+".1", " end\n")
+	checkLines(t, "new file not created yet", tl, []string{"old 1", "old 2 end"})
+
+	appendFile(t, fp+".1", "old 3\n")
+	appendFile(t, fp, "new 1\n")
+	checkLines(t, "new file created", tl, []string{"old 3", "new 1"})
+	appendFile(t, fp, "new 2\n")
+	checkLines(t, "after rotation", tl, []string{"new 2"})
+}
+
+func TestTailCopyTruncate(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "app.log")
+	appendFile(t, fp, "")
+
+	tl := newTailer(fp)
+	defer tl.close()
+	appendFile(t, fp, "a long line before truncation\nunterminated")
+	checkLines(t, "before truncation", tl, []string{"a long line before truncation"})
+
+	if err := os.Truncate(fp, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, fp, "after\n")
+	checkLines(t, "truncated", tl, []string{"after"})
+	appendFile(t, fp, "more\n")
+	checkLines(t, "after truncation", tl, []string{"more"})
+}
+
+func TestTailCreatedLater(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "app.log")
+
+	tl := newTailer(fp)
+	defer tl.close()
+	if _, err := tl.readLines(); err == nil {
+		t.Error("expected error on missing file")
+	}
+	// File created after start-up is read from the beginning
+	appendFile(t, fp, "first\nsecond\n")
+	checkLines(t, "created", tl, []string{"first", "second"})
+	appendFile(t, fp, "third\n")
+	checkLines(t, "append", tl, []string{"third"})
+}
+
+func TestTailLongLines(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "app.log")
+	appendFile(t, fp, "")
+
+	tl := newTailer(fp)
+	defer tl.close()
+	// Appended content is read in chunks of maxReadSize
+	line := strings.Repeat("a", 1023) + "\n"
+	appendFile(t, fp, strings.Repeat(line, 2*maxReadSize/len(line)))
+	total := 0
+	for i := 0; i < 3; i++ {
+		lines, err := tl.readLines()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) > maxReadSize/len(line) {
+			t.Errorf("%d: got %d lines in one read, want at most %d", i, len(lines), maxReadSize/len(line))
+		}
+		total += len(lines)
+	}
+	if total != 2*maxReadSize/len(line) {
+		t.Errorf("got %d lines, want %d", total, 2*maxReadSize/len(line))
+	}
+
+	// Unterminated line doesn't grow endlessly and is truncated when terminated
+	for i := 0; i < 3; i++ {
+		appendFile(t, fp, strings.Repeat("b", maxReadSize))
+		checkLines(t, "unterminated", tl, nil)
+		if len(tl.partial) > maxLineSize {
+			t.Fatalf("%d: got %d bytes partial line, want at most %d", i, len(tl.partial), maxLineSize)
+		}
+	}
+	appendFile(t, fp, "b\nshort\n")
+	checkLines(t, "terminated", tl, []string{strings.Repeat("b", maxLineSize), "short"})
+}
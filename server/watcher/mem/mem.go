@@ -5,6 +5,7 @@ import (
 	"time"
 
 	psmem "github.com/lnquy/gopsutil/mem"
+	"github.com/lnquy/nights-watch/server/util"
 	"github.com/sirupsen/logrus"
 )
 
@@ -13,9 +14,21 @@ type (
 		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
 	}
 
+	// Stats holds memory statistics. All sizes are in MB.
 	Stats struct {
-		Load  float64
-		Usage uint64
+		Load      float64 `json:"load"`
+		Usage     uint64  `json:"usage"`
+		Available uint64  `json:"available"`
+		Cached    uint64  `json:"cached"`
+		Buffers   uint64  `json:"buffers"`
+		Dirty     uint64  `json:"dirty"`
+		Writeback uint64  `json:"writeback"`
+
+		SwapLoad  float64 `json:"swapLoad"`
+		SwapUsage uint64  `json:"swapUsage"`
+
+		// Major page faults per second
+		MajorFaults uint64 `json:"majorFaults"`
 	}
 
 	watcher struct{}
@@ -31,6 +44,8 @@ func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *
 	stats := &Stats{}
 	go func() {
 		logrus.Infof("watcher: MEM watcher started")
+		var lastFaults uint64
+		var lastTime time.Time
 		for {
 			select {
 			case <-ticker.C:
@@ -41,6 +56,32 @@ func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *
 				}
 				stats.Load = vm.UsedPercent
 				stats.Usage = vm.Used / 1000000 // MB
+				stats.Available = vm.Available / 1000000
+				stats.Cached = vm.Cached / 1000000
+				stats.Buffers = vm.Buffers / 1000000
+				stats.Dirty = vm.Dirty / 1000000
+				stats.Writeback = vm.Writeback / 1000000
+
+				if sm, err := psmem.SwapMemory(); err != nil {
+					logrus.Debugf("mem: failed to get swap stats: %s", err)
+				} else {
+					stats.SwapLoad = sm.UsedPercent
+					stats.SwapUsage = sm.Used / 1000000
+				}
+
+				// Page faults counter is cumulative since boot so the first sample only
+				// initializes the counter and reports zero.
+				if faults, err := getMajorFaults(); err != nil {
+					logrus.Debugf("mem: failed to get major page faults: %s", err)
+				} else {
+					now := time.Now()
+					var elapsed float64
+					if !lastTime.IsZero() {
+						elapsed = now.Sub(lastTime).Seconds()
+					}
+					stats.MajorFaults = majorFaultRate(lastFaults, faults, elapsed)
+					lastFaults, lastTime = faults, now
+				}
 				statsChan <- stats
 			case <-ctx.Done():
 				close(statsChan)
@@ -52,3 +93,12 @@ func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *
 	}()
 	return statsChan
 }
+
+// majorFaultRate returns the major page faults per second between 2 samples,
+// zero on the first sample (elapsed is zero) or after the counter has been reset.
+func majorFaultRate(last, cur uint64, elapsed float64) uint64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return uint64(float64(util.Delta(last, cur)) / elapsed)
+}
@@ -0,0 +1,34 @@
+// +build linux
+
+package mem
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const vmstatFile = "/proc/vmstat"
+
+// getMajorFaults returns the total number of major page faults since boot.
+func getMajorFaults() (uint64, error) {
+	f, err := os.Open(vmstatFile)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "pgmajfault" {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("pgmajfault not found in %s", vmstatFile)
+}
@@ -0,0 +1,10 @@
+// +build !linux
+
+package mem
+
+import "errors"
+
+// TODO: Major page faults on Windows/Darwin
+func getMajorFaults() (uint64, error) {
+	return 0, errors.New("major page faults is not supported on this platform")
+}
@@ -0,0 +1,23 @@
+package mem
+
+import "testing"
+
+func TestMajorFaultRate(t *testing.T) {
+	tests := []struct {
+		last, cur uint64
+		elapsed   float64
+		want      uint64
+	}{
+		{0, 123456, 0, 0}, // First sample
+		{1000, 1500, 2, 250},
+		{1000, 1000, 1, 0},
+		{1000, 1001, 0.5, 2},
+		{5000000000, 20, 1, 0}, // Counter reset
+		{1000, 20, 1, 0},
+	}
+	for i, tt := range tests {
+		if got := majorFaultRate(tt.last, tt.cur, tt.elapsed); got != tt.want {
+			t.Errorf("%d: got %d, want %d", i, got, tt.want)
+		}
+	}
+}
@@ -2,9 +2,12 @@ package net
 
 import (
 	"context"
+	"path/filepath"
+	"sort"
 	"time"
 
 	psnet "github.com/lnquy/gopsutil/net"
+	"github.com/lnquy/nights-watch/server/util"
 	"github.com/sirupsen/logrus"
 )
 
@@ -13,39 +16,102 @@ type (
 		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
 	}
 
+	// Stats holds the network throughput (KB/s), errors and drops (per second) summed from
+	// all watched interfaces.
 	Stats struct {
-		Download uint64
-		Upload   uint64
+		Download   uint64           `json:"download"`
+		Upload     uint64           `json:"upload"`
+		Errors     float64          `json:"errors"`
+		Drops      float64          `json:"drops"`
+		TCP        TCPStats         `json:"tcp"`
+		Interfaces []InterfaceStats `json:"interfaces"`
 	}
 
-	watcher struct{}
+	InterfaceStats struct {
+		Name      string  `json:"name"`
+		Download  uint64  `json:"download"`  // KB/s
+		Upload    uint64  `json:"upload"`    // KB/s
+		BytesRecv uint64  `json:"bytesRecv"` // Total since boot
+		BytesSent uint64  `json:"bytesSent"` // Total since boot
+		RxErrors  float64 `json:"rxErrors"`  // Per second
+		TxErrors  float64 `json:"txErrors"`  // Per second
+		RxDrops   float64 `json:"rxDrops"`   // Per second
+		TxDrops   float64 `json:"txDrops"`   // Per second
+	}
+
+	// TCPStats holds the system wide TCP health counters.
+	TCPStats struct {
+		ActiveOpens    float64 `json:"activeOpens"`    // Per second
+		PassiveOpens   float64 `json:"passiveOpens"`   // Per second
+		Retransmits    float64 `json:"retransmits"`    // Retransmitted segments per second
+		RetransmitRate float64 `json:"retransmitRate"` // Percent of retransmitted segments over sent segments
+		Established    uint64  `json:"established"`    // Current established connections
+	}
+
+	// Raw cumulative TCP counters read from system
+	tcpCounters struct {
+		activeOpens  uint64
+		passiveOpens uint64
+		outSegs      uint64
+		retransSegs  uint64
+	}
+
+	watcher struct {
+		include []string
+		exclude []string
+	}
 )
 
-func NewWatcher() Watcher {
-	return &watcher{}
+// NewWatcher returns a network watcher which only watches on interfaces matched the include
+// glob patterns (all interfaces if empty) and not matched any of the exclude patterns.
+func NewWatcher(include, exclude []string) Watcher {
+	return &watcher{
+		include: include,
+		exclude: exclude,
+	}
 }
 
 func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
 	ticker := time.NewTicker(interval)
 	statsChan := make(chan *Stats, 10)
-	lastStats, stats := &Stats{}, &Stats{}
-	sec := uint64(interval / time.Second)
 
 	go func() {
 		logrus.Infof("watcher: NET watcher started")
+		// Counters are cumulative since boot so the first sample is only used to warm up
+		var last map[string]psnet.IOCountersStat
+		var lastTCP *tcpCounters
+		var lastTime time.Time
 		for {
 			select {
 			case <-ticker.C:
-				netStats, err := psnet.IOCounters(false)
+				netStats, err := psnet.IOCounters(true)
 				if err != nil {
 					logrus.Error(err)
+					continue
+				}
+				now := time.Now()
+				cur := make(map[string]psnet.IOCountersStat)
+				for _, ns := range netStats {
+					if w.isWatched(ns.Name) {
+						cur[ns.Name] = ns
+					}
+				}
+				curTCP, err := getTCPCounters()
+				if err != nil {
+					logrus.Debugf("net: failed to get TCP counters: %s", err)
+				}
+				if last != nil {
+					elapsed := now.Sub(lastTime).Seconds()
+					stats := getStats(last, cur, elapsed)
+					if lastTCP != nil && curTCP != nil {
+						stats.TCP = getTCPStats(lastTCP, curTCP, elapsed)
+					}
+					if stats.TCP.Established, err = countEstablished(); err != nil {
+						logrus.Debugf("net: failed to count established TCP connections: %s", err)
+					}
 					statsChan <- stats
 				}
-				stats.Download = (netStats[0].BytesRecv - lastStats.Download) / sec / 1000
-				stats.Upload = (netStats[0].BytesSent - lastStats.Upload) / sec / 1000
-				lastStats.Download = netStats[0].BytesRecv
-				lastStats.Upload = netStats[0].BytesSent
-				statsChan <- stats
+				last, lastTCP, lastTime = cur, curTCP, now
 			case <-ctx.Done():
 				close(statsChan)
 				ticker.Stop()
@@ -56,3 +122,65 @@ func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *
 	}()
 	return statsChan
 }
+
+func (w *watcher) isWatched(name string) bool {
+	for _, p := range w.exclude {
+		if ok, _ := filepath.Match(p, name); ok {
+			return false
+		}
+	}
+	if len(w.include) == 0 {
+		return true
+	}
+	for _, p := range w.include {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func getStats(last, cur map[string]psnet.IOCountersStat, elapsed float64) *Stats {
+	stats := &Stats{Interfaces: make([]InterfaceStats, 0, len(cur))}
+	for name, c := range cur {
+		is := InterfaceStats{
+			Name:      name,
+			BytesRecv: c.BytesRecv,
+			BytesSent: c.BytesSent,
+		}
+		// Newly appeared interface has no previous sample, report zero throughput for now
+		if l, ok := last[name]; ok && elapsed > 0 {
+			is.Download = uint64(float64(util.Delta(l.BytesRecv, c.BytesRecv)) / elapsed / 1000)
+			is.Upload = uint64(float64(util.Delta(l.BytesSent, c.BytesSent)) / elapsed / 1000)
+			is.RxErrors = float64(util.Delta(l.Errin, c.Errin)) / elapsed
+			is.TxErrors = float64(util.Delta(l.Errout, c.Errout)) / elapsed
+			is.RxDrops = float64(util.Delta(l.Dropin, c.Dropin)) / elapsed
+			is.TxDrops = float64(util.Delta(l.Dropout, c.Dropout)) / elapsed
+		}
+		stats.Download += is.Download
+		stats.Upload += is.Upload
+		stats.Errors += is.RxErrors + is.TxErrors
+		stats.Drops += is.RxDrops + is.TxDrops
+		stats.Interfaces = append(stats.Interfaces, is)
+	}
+	sort.Slice(stats.Interfaces, func(i, j int) bool {
+		return stats.Interfaces[i].Name < stats.Interfaces[j].Name
+	})
+	return stats
+}
+
+func getTCPStats(last, cur *tcpCounters, elapsed float64) TCPStats {
+	ts := TCPStats{}
+	if elapsed <= 0 {
+		return ts
+	}
+	outSegs, retrans := util.Delta(last.outSegs, cur.outSegs), util.Delta(last.retransSegs, cur.retransSegs)
+	ts.ActiveOpens = float64(util.Delta(last.activeOpens, cur.activeOpens)) / elapsed
+	ts.PassiveOpens = float64(util.Delta(last.passiveOpens, cur.passiveOpens)) / elapsed
+	ts.Retransmits = float64(retrans) / elapsed
+	if outSegs > 0 {
+		ts.RetransmitRate = float64(retrans) / float64(outSegs) * 100
+	}
+	return ts
+}
+
@@ -0,0 +1,102 @@
+// +build linux
+
+package net
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	snmpFile = "/proc/net/snmp"
+	tcpFiles = []string{"/proc/net/tcp", "/proc/net/tcp6"}
+)
+
+const tcpEstablished = "01" // TCP_ESTABLISHED state in /proc/net/tcp
+
+func getTCPCounters() (*tcpCounters, error) {
+	f, err := os.Open(snmpFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseSNMP(f)
+}
+
+// parseSNMP parses the TCP counters from /proc/net/snmp, where each protocol has a header line
+// and a values line, e.g.:
+//
+//	Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens ... OutSegs RetransSegs ...
+//	Tcp: 1 200 120000 -1 8841 276 ... 612377 1071 ...
+func parseSNMP(r io.Reader) (*tcpCounters, error) {
+	var header []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "Tcp:" {
+			continue
+		}
+		if header == nil {
+			header = fields
+			continue
+		}
+		if len(fields) != len(header) {
+			return nil, fmt.Errorf("net: mismatched TCP header and values in %s", snmpFile)
+		}
+		values := make(map[string]uint64)
+		for i := 1; i < len(fields); i++ {
+			if v, err := strconv.ParseUint(fields[i], 10, 64); err == nil {
+				values[header[i]] = v
+			}
+		}
+		return &tcpCounters{
+			activeOpens:  values["ActiveOpens"],
+			passiveOpens: values["PassiveOpens"],
+			outSegs:      values["OutSegs"],
+			retransSegs:  values["RetransSegs"],
+		}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("net: TCP counters not found in %s", snmpFile)
+}
+
+func countEstablished() (uint64, error) {
+	var total uint64
+	for _, fp := range tcpFiles {
+		f, err := os.Open(fp)
+		if err != nil {
+			if os.IsNotExist(err) { // IPv6 disabled
+				continue
+			}
+			return 0, err
+		}
+		n, err := parseTCPTable(f)
+		f.Close()
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// parseTCPTable counts the established connections in /proc/net/tcp{,6}.
+// The 4th column is the connection state in hex.
+func parseTCPTable(r io.Reader) (uint64, error) {
+	var count uint64
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // Skip header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 3 && fields[3] == tcpEstablished {
+			count++
+		}
+	}
+	return count, scanner.Err()
+}
@@ -0,0 +1,63 @@
+// +build linux
+
+package net
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseSNMP(t *testing.T) {
+	f, err := os.Open("testdata/snmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	c, err := parseSNMP(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := tcpCounters{activeOpens: 88412, passiveOpens: 2761, outSegs: 6120377, retransSegs: 10713}
+	if *c != want {
+		t.Errorf("got %+v, want %+v", *c, want)
+	}
+
+	invalid := map[string]string{
+		"no TCP":     "Udp: InDatagrams NoPorts\nUdp: 1 2\n",
+		"no values":  "Tcp: RtoAlgorithm ActiveOpens\n",
+		"mismatched": "Tcp: RtoAlgorithm ActiveOpens PassiveOpens\nTcp: 1 2\n",
+	}
+	for name, s := range invalid {
+		if c, err := parseSNMP(strings.NewReader(s)); err == nil {
+			t.Errorf("%s: expected error, got %+v", name, c)
+		}
+	}
+}
+
+func TestParseTCPTable(t *testing.T) {
+	tests := map[string]uint64{
+		"testdata/tcp":  3,
+		"testdata/tcp6": 1,
+	}
+	for fp, want := range tests {
+		f, err := os.Open(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := parseTCPTable(f)
+		f.Close()
+		if err != nil || n != want {
+			t.Errorf("%s: got %d established connections (%v), want %d", fp, n, err, want)
+		}
+	}
+}
+
+func TestCountEstablished(t *testing.T) {
+	defer func(files []string) { tcpFiles = files }(tcpFiles)
+	// Missing tcp6 means IPv6 is disabled
+	tcpFiles = []string{"testdata/tcp", "testdata/tcp6", "testdata/missing"}
+	if n, err := countEstablished(); err != nil || n != 4 {
+		t.Errorf("got %d (%v), want 4", n, err)
+	}
+}
@@ -0,0 +1,14 @@
+// +build !linux
+
+package net
+
+import "errors"
+
+// TODO: TCP counters on Windows/Darwin
+func getTCPCounters() (*tcpCounters, error) {
+	return nil, errors.New("TCP counters is not supported on this platform")
+}
+
+func countEstablished() (uint64, error) {
+	return 0, errors.New("TCP connections counting is not supported on this platform")
+}
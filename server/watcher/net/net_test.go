@@ -0,0 +1,53 @@
+package net
+
+import (
+	"math"
+	"testing"
+
+	psnet "github.com/lnquy/gopsutil/net"
+)
+
+func TestGetStats(t *testing.T) {
+	last := map[string]psnet.IOCountersStat{
+		"eth0":  {Name: "eth0", BytesRecv: 1000000, BytesSent: 500000, Errin: 1, Dropin: 10},
+		"wlan0": {Name: "wlan0", BytesRecv: math.MaxUint32 - 999999, BytesSent: 0},
+	}
+	cur := map[string]psnet.IOCountersStat{
+		"eth0":  {Name: "eth0", BytesRecv: 3000000, BytesSent: 1500000, Errin: 5, Dropin: 14, Dropout: 2},
+		"wlan0": {Name: "wlan0", BytesRecv: 1000000, BytesSent: 2000000},
+		"tun0":  {Name: "tun0", BytesRecv: 42000000}, // Newly appeared
+	}
+	s := getStats(last, cur, 2)
+	if len(s.Interfaces) != 3 || s.Interfaces[0].Name != "eth0" || s.Interfaces[1].Name != "tun0" {
+		t.Fatalf("unexpected interfaces: %+v", s.Interfaces)
+	}
+	eth0, tun0, wlan0 := s.Interfaces[0], s.Interfaces[1], s.Interfaces[2]
+	if eth0.Download != 1000 || eth0.Upload != 500 || eth0.RxErrors != 2 || eth0.RxDrops != 2 || eth0.TxDrops != 1 {
+		t.Errorf("unexpected eth0 stats: %+v", eth0)
+	}
+	if wlan0.Download != 1000 || wlan0.Upload != 1000 { // Wrapped 32 bits counter
+		t.Errorf("unexpected wlan0 stats: %+v", wlan0)
+	}
+	if tun0.Download != 0 || tun0.BytesRecv != 42000000 {
+		t.Errorf("unexpected tun0 stats: %+v", tun0)
+	}
+	if s.Download != 2000 || s.Upload != 1500 || s.Errors != 2 || s.Drops != 3 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+}
+
+func TestGetTCPStats(t *testing.T) {
+	last := &tcpCounters{activeOpens: 100, passiveOpens: 10, outSegs: 100000, retransSegs: 50}
+	cur := &tcpCounters{activeOpens: 120, passiveOpens: 30, outSegs: 120000, retransSegs: 53}
+	ts := getTCPStats(last, cur, 10)
+	if ts.ActiveOpens != 2 || ts.PassiveOpens != 2 || ts.Retransmits != 0.3 {
+		t.Errorf("unexpected stats: %+v", ts)
+	}
+	// Fractions of percent are kept for the threshold
+	if math.Abs(ts.RetransmitRate-0.015) > 1e-9 {
+		t.Errorf("RetransmitRate = %v, want 0.015", ts.RetransmitRate)
+	}
+	if ts := getTCPStats(last, last, 10); ts.RetransmitRate != 0 {
+		t.Errorf("expected zero rate without sent segments, got %+v", ts)
+	}
+}
@@ -0,0 +1,109 @@
+// Package plugin runs long-running collectors (plugins) as child processes. A plugin can be written in any language,
+// it speaks a line-delimited JSON protocol, one message per line:
+//
+// Server to plugin (stdin), sent once after the plugin started. Plugin should exit when stdin is closed:
+//
+//	{"type": "configure", "name": "queue", "interval": 1000}          // Interval in milliseconds
+//
+// Plugin to server (stdout):
+//
+//	{"type": "describe", "metrics": [{"name": "length", "unit": "jobs", "description": "Pending jobs"}]}
+//	{"type": "sample", "values": {"length": 42, "workers.busy": 3}}
+//	{"type": "error", "message": "failed to connect to queue"}
+//
+// Sample values are reported as <plugin name>.<key> metrics (e.g.: queue.length) until they're replaced by newer samples
+// or the plugin exited. Stderr of plugins is logged at debug level.
+// Plugins are restarted with exponential backoff when they exited.
+package plugin
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/lnquy/nights-watch/server/watcher/custom"
+	"github.com/sirupsen/logrus"
+)
+
+// Restart backoff of exited plugins, it's reset when a plugin has been running longer than MaxBackoff.
+var (
+	MinBackoff = time.Second
+	MaxBackoff = time.Minute
+)
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	Plugin struct {
+		Name string
+		Path string
+		Args []string
+	}
+
+	Stats struct {
+		Metrics []custom.Metric `json:"metrics"`
+		Plugins []Status        `json:"plugins"`
+	}
+
+	Status struct {
+		Name     string        `json:"name"`
+		Running  bool          `json:"running"`
+		Restarts int           `json:"restarts"`
+		Error    string        `json:"error,omitempty"` // Last error reported by or occurred to the plugin
+		Metrics  []Description `json:"metrics"`
+	}
+
+	Description struct {
+		Name        string `json:"name"`
+		Unit        string `json:"unit,omitempty"`
+		Description string `json:"description,omitempty"`
+	}
+
+	watcher struct {
+		plugins []Plugin
+	}
+)
+
+func NewWatcher(plugins []Plugin) Watcher {
+	return &watcher{plugins: plugins}
+}
+
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	runners := make([]*runner, 0, len(w.plugins))
+	for _, p := range w.plugins {
+		r := newRunner(p)
+		go r.supervise(ctx, interval)
+		runners = append(runners, r)
+	}
+	go func() {
+		logrus.Infof("watcher: PLUGIN watcher started")
+		for {
+			select {
+			case <-ticker.C:
+				stats := &Stats{
+					Metrics: make([]custom.Metric, 0),
+					Plugins: make([]Status, 0, len(runners)),
+				}
+				for _, r := range runners {
+					status, metrics := r.snapshot()
+					stats.Plugins = append(stats.Plugins, status)
+					stats.Metrics = append(stats.Metrics, metrics...)
+				}
+				sort.Slice(stats.Metrics, func(i, j int) bool {
+					return stats.Metrics[i].Name < stats.Metrics[j].Name
+				})
+				statsChan <- stats
+			case <-ctx.Done():
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: PLUGIN watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
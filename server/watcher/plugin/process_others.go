@@ -0,0 +1,21 @@
+// +build !windows
+
+package plugin
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// command returns the command of a plugin which runs in its own process group,
+// so its children are also killed with it, otherwise they keep stdout and stderr open.
+func command(p Plugin) *exec.Cmd {
+	cmd := exec.Command(p.Path, p.Args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+// kill kills the process group of a started plugin.
+func kill(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
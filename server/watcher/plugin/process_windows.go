@@ -0,0 +1,13 @@
+// +build windows
+
+package plugin
+
+import "os/exec"
+
+func command(p Plugin) *exec.Cmd {
+	return exec.Command(p.Path, p.Args...)
+}
+
+func kill(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
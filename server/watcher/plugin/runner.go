@@ -0,0 +1,218 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lnquy/nights-watch/server/watcher/custom"
+	"github.com/sirupsen/logrus"
+)
+
+// Time for a plugin to exit after its stdin is closed before it's killed
+var stopTimeout = 3 * time.Second
+
+const (
+	maxMessageSize = 1024 * 1024 // Plugin is restarted when it writes a longer message line
+	maxStderrLine  = 4096        // Longer stderr lines are logged in parts
+)
+
+type (
+	message struct {
+		Type     string             `json:"type"`
+		Name     string             `json:"name,omitempty"`
+		Interval int64              `json:"interval,omitempty"`
+		Metrics  []Description      `json:"metrics,omitempty"`
+		Values   map[string]float64 `json:"values,omitempty"`
+		Message  string             `json:"message,omitempty"`
+	}
+
+	// runner runs and restarts a plugin, it holds the latest state reported by the plugin.
+	runner struct {
+		plugin Plugin
+
+		mu     sync.Mutex
+		status Status
+		values map[string]float64
+	}
+)
+
+func newRunner(p Plugin) *runner {
+	return &runner{
+		plugin: p,
+		status: Status{Name: p.Name, Metrics: make([]Description, 0)},
+		values: make(map[string]float64),
+	}
+}
+
+// supervise runs the plugin until ctx is done, restarts it with backoff when it exited.
+func (r *runner) supervise(ctx context.Context, interval time.Duration) {
+	var backoff time.Duration
+	for {
+		start := time.Now()
+		err := r.run(ctx, interval)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("exited")
+		}
+		backoff = nextBackoff(backoff, time.Since(start))
+		logrus.Errorf("plugin: %s: %s, restarting in %s", r.plugin.Name, err, backoff)
+		r.mu.Lock()
+		r.status.Running, r.status.Error = false, err.Error()
+		r.values = make(map[string]float64)
+		r.mu.Unlock()
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		r.mu.Lock()
+		r.status.Restarts++
+		r.mu.Unlock()
+	}
+}
+
+// nextBackoff returns the backoff before restarting a plugin which ran for the duration,
+// it's doubled on every restart and reset when the plugin was healthy for a while.
+func nextBackoff(last, ran time.Duration) time.Duration {
+	if last == 0 || ran > MaxBackoff {
+		return MinBackoff
+	}
+	if last *= 2; last > MaxBackoff {
+		return MaxBackoff
+	}
+	return last
+}
+
+// run starts the plugin and handles its messages until it exited.
+func (r *runner) run(ctx context.Context, interval time.Duration) error {
+	cmd := command(r.plugin)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = &stderrLogger{name: r.plugin.Name}
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start: %s", err)
+	}
+	logrus.Infof("plugin: %s started (PID %d)", r.plugin.Name, cmd.Process.Pid)
+	r.mu.Lock()
+	r.status.Running, r.status.Error = true, ""
+	r.mu.Unlock()
+
+	// Stop the plugin when the watcher stopped: close stdin first then kill it if it's still running
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stdin.Close()
+			select {
+			case <-time.After(stopTimeout):
+				kill(cmd)
+			case <-exited:
+			}
+		case <-exited:
+		}
+	}()
+
+	b, _ := json.Marshal(message{
+		Type:     "configure",
+		Name:     r.plugin.Name,
+		Interval: interval.Nanoseconds() / int64(time.Millisecond),
+	})
+	if _, err = stdin.Write(append(b, '\n')); err != nil {
+		logrus.Errorf("plugin: %s: failed to configure: %s", r.plugin.Name, err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		r.handle(scanner.Bytes())
+	}
+	if err = scanner.Err(); err != nil {
+		// Plugin would block on writing messages which are no longer read
+		kill(cmd)
+		cmd.Wait()
+		return fmt.Errorf("failed to read messages: %s", err)
+	}
+	if err = cmd.Wait(); err != nil {
+		return fmt.Errorf("exited: %s", err)
+	}
+	return nil
+}
+
+func (r *runner) handle(line []byte) {
+	var m message
+	if err := json.Unmarshal(line, &m); err != nil {
+		logrus.Debugf("plugin: %s: invalid message: %q", r.plugin.Name, line)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch m.Type {
+	case "describe":
+		r.status.Metrics = m.Metrics
+	case "sample":
+		for k, v := range m.Values {
+			r.values[k] = v
+		}
+		r.status.Error = ""
+	case "error":
+		logrus.Errorf("plugin: %s: %s", r.plugin.Name, m.Message)
+		r.status.Error = m.Message
+	default:
+		logrus.Debugf("plugin: %s: unknown message type: %q", r.plugin.Name, m.Type)
+	}
+}
+
+// stderrLogger logs stderr of a plugin line by line, lines longer than maxStderrLine are logged in parts.
+type stderrLogger struct {
+	name string
+	buf  []byte
+}
+
+func (l *stderrLogger) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		logrus.Debugf("plugin: %s: %s", l.name, l.buf[:i])
+		l.buf = l.buf[i+1:]
+	}
+	for len(l.buf) >= maxStderrLine {
+		logrus.Debugf("plugin: %s: %s", l.name, l.buf[:maxStderrLine])
+		l.buf = l.buf[maxStderrLine:]
+	}
+	return len(p), nil
+}
+
+// snapshot returns the current status and metrics of the plugin.
+func (r *runner) snapshot() (Status, []custom.Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.status
+	s.Metrics = append(make([]Description, 0, len(r.status.Metrics)), r.status.Metrics...)
+	metrics := make([]custom.Metric, 0, len(r.values))
+	for k, v := range r.values {
+		metrics = append(metrics, custom.Metric{
+			Name:    r.plugin.Name + "." + k,
+			Command: r.plugin.Name,
+			Value:   v,
+		})
+	}
+	return s, metrics
+}
@@ -0,0 +1,66 @@
+// +build linux
+
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lnquy/nights-watch/server/watcher/custom"
+)
+
+func TestRun(t *testing.T) {
+	r := newRunner(Plugin{Name: "echo", Path: "/bin/sh", Args: []string{"testdata/echo.sh"}})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.run(ctx, time.Second) }()
+
+	// Plugin keeps running until stdin is closed
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if status, metrics := r.snapshot(); len(metrics) > 0 || status.Error != "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("got no sample from plugin")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	status, metrics := r.snapshot()
+	if !status.Running || status.Error != "" {
+		t.Errorf("got status %+v, want running without error", status)
+	}
+	if want := []Description{{Name: "length", Unit: "jobs"}}; !reflect.DeepEqual(status.Metrics, want) {
+		t.Errorf("got descriptions %+v, want %+v", status.Metrics, want)
+	}
+	if want := []custom.Metric{{Name: "echo.configured", Command: "echo", Value: 1}}; !reflect.DeepEqual(metrics, want) {
+		t.Errorf("got metrics %+v, want %+v", metrics, want)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("got %s, want plugin exited when stdin is closed", err)
+		}
+	case <-time.After(stopTimeout + 5*time.Second):
+		t.Fatal("plugin is still running after stopped")
+	}
+}
+
+func TestRunLongMessage(t *testing.T) {
+	r := newRunner(Plugin{Name: "long", Path: "/bin/sh", Args: []string{"testdata/long-message.sh"}})
+	done := make(chan error)
+	go func() { done <- r.run(context.Background(), time.Second) }()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "too long") {
+			t.Errorf("got %v, want token too long error", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("plugin is still running after writing a too long message")
+	}
+}
@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lnquy/nights-watch/server/watcher/custom"
+)
+
+func TestHandle(t *testing.T) {
+	r := newRunner(Plugin{Name: "queue"})
+	tests := []struct {
+		line    string
+		metrics []Description
+		values  []custom.Metric
+		err     string
+	}{
+		{
+			`{"type": "describe", "metrics": [{"name": "length", "unit": "jobs", "description": "Pending jobs"}]}`,
+			[]Description{{Name: "length", Unit: "jobs", Description: "Pending jobs"}},
+			[]custom.Metric{},
+			"",
+		},
+		{
+			`{"type": "sample", "values": {"length": 42}}`,
+			[]Description{{Name: "length", Unit: "jobs", Description: "Pending jobs"}},
+			[]custom.Metric{{Name: "queue.length", Command: "queue", Value: 42}},
+			"",
+		},
+		{
+			`{"type": "error", "message": "failed to connect to queue"}`,
+			[]Description{{Name: "length", Unit: "jobs", Description: "Pending jobs"}},
+			[]custom.Metric{{Name: "queue.length", Command: "queue", Value: 42}},
+			"failed to connect to queue",
+		},
+		{ // Sample clears the error and replaces values of the same keys
+			`{"type": "sample", "values": {"length": 7}}`,
+			[]Description{{Name: "length", Unit: "jobs", Description: "Pending jobs"}},
+			[]custom.Metric{{Name: "queue.length", Command: "queue", Value: 7}},
+			"",
+		},
+		{ // Invalid and unknown messages are ignored
+			`{"type": "sample", "values": {"length": `,
+			[]Description{{Name: "length", Unit: "jobs", Description: "Pending jobs"}},
+			[]custom.Metric{{Name: "queue.length", Command: "queue", Value: 7}},
+			"",
+		},
+		{
+			`{"type": "progress", "values": {"length": 1}}`,
+			[]Description{{Name: "length", Unit: "jobs", Description: "Pending jobs"}},
+			[]custom.Metric{{Name: "queue.length", Command: "queue", Value: 7}},
+			"",
+		},
+		{
+			`{"type": "describe", "metrics": []}`,
+			[]Description{},
+			[]custom.Metric{{Name: "queue.length", Command: "queue", Value: 7}},
+			"",
+		},
+	}
+	for i, tt := range tests {
+		r.handle([]byte(tt.line))
+		status, metrics := r.snapshot()
+		if !reflect.DeepEqual(status.Metrics, tt.metrics) {
+			t.Errorf("%d: got descriptions %+v, want %+v", i, status.Metrics, tt.metrics)
+		}
+		if !reflect.DeepEqual(metrics, tt.values) {
+			t.Errorf("%d: got metrics %+v, want %+v", i, metrics, tt.values)
+		}
+		if status.Error != tt.err {
+			t.Errorf("%d: got error %q, want %q", i, status.Error, tt.err)
+		}
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	defer func(min, max time.Duration) { MinBackoff, MaxBackoff = min, max }(MinBackoff, MaxBackoff)
+	MinBackoff, MaxBackoff = time.Second, 10*time.Second
+
+	tests := []struct {
+		last, ran time.Duration
+		want      time.Duration
+	}{
+		{0, 0, time.Second}, // First restart
+		{time.Second, 0, 2 * time.Second},
+		{2 * time.Second, time.Second, 4 * time.Second},
+		{8 * time.Second, 0, 10 * time.Second},
+		{10 * time.Second, 10 * time.Second, 10 * time.Second},
+		{10 * time.Second, 11 * time.Second, time.Second}, // Healthy for longer than MaxBackoff
+		{0, time.Hour, time.Second},
+	}
+	for i, tt := range tests {
+		if got := nextBackoff(tt.last, tt.ran); got != tt.want {
+			t.Errorf("%d: got %s, want %s", i, got, tt.want)
+		}
+	}
+}
+
+func TestStderrLogger(t *testing.T) {
+	l := &stderrLogger{name: "queue"}
+	if n, err := l.Write([]byte("first\nsecond\nthi")); n != 16 || err != nil {
+		t.Fatalf("got %d (error: %v), want 16", n, err)
+	}
+	if string(l.buf) != "thi" {
+		t.Errorf("got buffered %q, want incomplete line", l.buf)
+	}
+	l.Write([]byte("rd\n"))
+	if len(l.buf) != 0 {
+		t.Errorf("got buffered %q, want empty", l.buf)
+	}
+
+	// Lines without newline don't grow the buffer endlessly
+	for i := 0; i < 100; i++ {
+		l.Write(bytes.Repeat([]byte("a"), 1000))
+		if len(l.buf) >= maxStderrLine {
+			t.Fatalf("%d: got %d bytes buffered, want less than %d", i, len(l.buf), maxStderrLine)
+		}
+	}
+	l.Write([]byte(strings.Repeat("b", 3*maxStderrLine) + "\nc"))
+	if string(l.buf) != "c" {
+		t.Errorf("got buffered %q, want c", l.buf)
+	}
+}
@@ -0,0 +1,10 @@
+#!/bin/sh
+# Reports the configure message, then exits when stdin is closed
+read -r configure
+echo '{"type": "describe", "metrics": [{"name": "length", "unit": "jobs"}]}'
+case "$configure" in
+*'"name":"echo"'*'"interval":1000'*) echo '{"type": "sample", "values": {"configured": 1}}' ;;
+*) echo '{"type": "error", "message": "invalid configure message"}' ;;
+esac
+echo "echo plugin started" >&2
+cat > /dev/null
@@ -0,0 +1,4 @@
+#!/bin/sh
+# Writes a message longer than the scanner buffer and keeps running
+head -c 2000000 /dev/zero | tr '\0' 'a'
+exec sleep 60
@@ -0,0 +1,155 @@
+package power
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Energy ledger is persisted at most once per saveInterval and when the watcher stopped
+var saveInterval = time.Minute
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	// Stats holds the CPU power measured by RAPL (Running Average Power Limit) energy counters.
+	// Energy and cost only count CPU packages and DRAM, not the whole system.
+	Stats struct {
+		Package     float64  `json:"package"` // Watts, sum of all CPU packages
+		Core        float64  `json:"core"`    // Watts, part of Package
+		DRAM        float64  `json:"dram"`    // Watts
+		Domains     []Domain `json:"domains"`
+		EnergyToday float64  `json:"energyToday"` // kWh
+		EnergyMonth float64  `json:"energyMonth"` // kWh
+		CostToday   float64  `json:"costToday"`
+		CostMonth   float64  `json:"costMonth"`
+	}
+
+	Domain struct {
+		Zone  string  `json:"zone"` // Powercap zone, e.g.: intel-rapl:0:1
+		Name  string  `json:"name"` // E.g.: package-0, core, uncore, dram
+		Power float64 `json:"power"`
+	}
+
+	watcher struct {
+		energyFile string
+		tariff     float64
+	}
+)
+
+// NewWatcher returns a RAPL power watcher which persists the consumed energy to energyFile
+// and calculates cost with tariff (price per kWh).
+func NewWatcher(energyFile string, tariff float64) Watcher {
+	return &watcher{
+		energyFile: energyFile,
+		tariff:     tariff,
+	}
+}
+
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	go func() {
+		logrus.Infof("watcher: POWER watcher started")
+		c := newCollector()
+		l := loadLedger(w.energyFile)
+		lastSave := time.Now()
+		for {
+			select {
+			case <-ticker.C:
+				stats, joules, err := c.collect()
+				if err != nil {
+					logrus.Error(err)
+					continue
+				}
+				if stats == nil { // First sample
+					continue
+				}
+				now := time.Now()
+				l.add(now, joules/3600)
+				stats.EnergyToday, stats.EnergyMonth = l.DayEnergy/1000, l.MonthEnergy/1000
+				stats.CostToday, stats.CostMonth = stats.EnergyToday*w.tariff, stats.EnergyMonth*w.tariff
+				if now.Sub(lastSave) >= saveInterval {
+					l.save(w.energyFile)
+					lastSave = now
+				}
+				statsChan <- stats
+			case <-ctx.Done():
+				l.save(w.energyFile)
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: POWER watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
+
+// ledger accumulates the energy consumed in the current day and month.
+type ledger struct {
+	Day         string  `json:"day"`       // 2006-01-02
+	DayEnergy   float64 `json:"dayEnergy"` // Wh
+	Month       string  `json:"month"`     // 2006-01
+	MonthEnergy float64 `json:"monthEnergy"`
+}
+
+// loadLedger returns the ledger persisted in fp, or an empty one if it's not readable.
+func loadLedger(fp string) *ledger {
+	l := &ledger{}
+	b, err := ioutil.ReadFile(fp)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Warnf("power: failed to read energy file, energy is counted from now: %s", err)
+		}
+		return l
+	}
+	if err = json.Unmarshal(b, l); err != nil {
+		logrus.Warnf("power: failed to parse energy file, energy is counted from now: %s", err)
+		return &ledger{}
+	}
+	return l
+}
+
+// add adds the energy (Wh) consumed until now, the day/month totals are reset when a new day/month begins.
+func (l *ledger) add(now time.Time, wh float64) {
+	if day := now.Format("2006-01-02"); l.Day != day {
+		l.Day, l.DayEnergy = day, 0
+	}
+	if month := now.Format("2006-01"); l.Month != month {
+		l.Month, l.MonthEnergy = month, 0
+	}
+	l.DayEnergy += wh
+	l.MonthEnergy += wh
+}
+
+func (l *ledger) save(fp string) {
+	if l.Day == "" { // Nothing counted yet
+		return
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		logrus.Errorf("power: failed to marshal energy: %s", err)
+		return
+	}
+	if err = ioutil.WriteFile(fp, b, 0644); err != nil {
+		logrus.Errorf("power: failed to write energy file: %s", err)
+	}
+}
+
+// energyDelta returns the energy consumed between 2 samples of a counter which wraps around at maxRange.
+func energyDelta(last, cur, maxRange uint64) uint64 {
+	if cur >= last {
+		return cur - last
+	}
+	if maxRange < last { // Unknown range, only the energy after wrapping is known
+		return cur
+	}
+	return maxRange - last + cur
+}
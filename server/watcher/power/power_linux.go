@@ -0,0 +1,87 @@
+// +build linux
+
+package power
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lnquy/nights-watch/server/util"
+)
+
+var powercapRoot = "/sys/class/powercap"
+
+type collector struct {
+	last     map[string]uint64 // Energy counter (µJ) of each zone
+	lastTime time.Time
+}
+
+func newCollector() *collector {
+	return &collector{}
+}
+
+// collect reads the RAPL energy counters and calculates the power since the last sample.
+// Returns the stats and the energy (Joules) consumed by CPU packages and DRAM, nil stats on the first sample.
+//
+// Both Intel and AMD (kernel 5.8+) CPUs expose RAPL zones as intel-rapl:<package>[:<subzone>].
+// Zones of the intel-rapl-mmio interface duplicate the package zones so they're not counted.
+func (c *collector) collect() (*Stats, float64, error) {
+	zones, err := filepath.Glob(filepath.Join(powercapRoot, "intel-rapl:*"))
+	if err != nil {
+		return nil, 0, fmt.Errorf("power: failed to list RAPL zones: %s", err)
+	}
+	if len(zones) == 0 {
+		return nil, 0, fmt.Errorf("power: no RAPL zones found in %s", powercapRoot)
+	}
+	sort.Strings(zones)
+
+	now := time.Now()
+	elapsed := now.Sub(c.lastTime).Seconds()
+	cur := make(map[string]uint64, len(zones))
+	stats := &Stats{Domains: make([]Domain, 0, len(zones))}
+	joules := 0.0
+	for _, dir := range zones {
+		zone := filepath.Base(dir)
+		energy, err := util.ReadUint(filepath.Join(dir, "energy_uj"))
+		if err != nil {
+			if os.IsPermission(err) { // Only readable by root since kernel 5.10
+				return nil, 0, fmt.Errorf("power: failed to read RAPL energy counters, root permission is required: %s", err)
+			}
+			continue
+		}
+		cur[zone] = energy
+		last, ok := c.last[zone]
+		if !ok || elapsed <= 0 {
+			continue
+		}
+		maxRange, _ := util.ReadUint(filepath.Join(dir, "max_energy_range_uj"))
+		j := float64(energyDelta(last, energy, maxRange)) / 1000000
+		d := Domain{
+			Zone:  zone,
+			Name:  util.ReadString(filepath.Join(dir, "name")),
+			Power: j / elapsed,
+		}
+		switch {
+		case strings.HasPrefix(d.Name, "package"):
+			stats.Package += d.Power
+			joules += j
+		case d.Name == "core":
+			stats.Core += d.Power
+		case d.Name == "dram":
+			stats.DRAM += d.Power
+			joules += j
+		}
+		stats.Domains = append(stats.Domains, d)
+	}
+
+	first := c.last == nil
+	c.last, c.lastTime = cur, now
+	if first {
+		return nil, 0, nil
+	}
+	return stats, joules, nil
+}
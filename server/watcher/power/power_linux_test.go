@@ -0,0 +1,92 @@
+// +build linux
+
+package power
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// copyPowercap copies the RAPL zones in testdata/powercap to a temporary powercap root,
+// fixtures aren't named by zone since colons aren't allowed in file names on every OS.
+func copyPowercap(t *testing.T, zones map[string]string) string {
+	root, err := ioutil.TempDir("", "powercap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for zone, fixture := range zones {
+		dir := filepath.Join(root, zone)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		files, err := ioutil.ReadDir(filepath.Join("testdata", "powercap", fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			b, err := ioutil.ReadFile(filepath.Join("testdata", "powercap", fixture, f.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, f.Name()), b, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return root
+}
+
+func TestCollect(t *testing.T) {
+	defer func(root string) { powercapRoot = root }(powercapRoot)
+	powercapRoot = copyPowercap(t, map[string]string{
+		"intel-rapl:0":   "package-0",
+		"intel-rapl:0:0": "core",
+		"intel-rapl:0:1": "dram",
+	})
+	defer os.RemoveAll(powercapRoot)
+
+	c := newCollector()
+	if stats, _, err := c.collect(); err != nil || stats != nil {
+		t.Fatalf("got %+v (error: %v) on first sample, want nil", stats, err)
+	}
+	// Package counter wrapped at max_energy_range_uj, core counter wrapped with unknown range
+	for zone, energy := range map[string]string{"intel-rapl:0": "2000000", "intel-rapl:0:0": "100000", "intel-rapl:0:1": "6000000"} {
+		if err := ioutil.WriteFile(filepath.Join(powercapRoot, zone, "energy_uj"), []byte(energy+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.lastTime = c.lastTime.Add(-time.Second)
+	stats, joules, err := c.collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joules != 4 { // Package and DRAM
+		t.Errorf("got %v joules, want 4", joules)
+	}
+	want := []Domain{{"intel-rapl:0", "package-0", 3}, {"intel-rapl:0:0", "core", 0.1}, {"intel-rapl:0:1", "dram", 1}}
+	if len(stats.Domains) != len(want) {
+		t.Fatalf("got domains %+v, want %+v", stats.Domains, want)
+	}
+	for i, d := range stats.Domains {
+		// Elapsed time is slightly longer than 1s
+		if d.Zone != want[i].Zone || d.Name != want[i].Name || d.Power > want[i].Power || d.Power < want[i].Power*0.9 {
+			t.Errorf("%d: got %+v, want %+v", i, d, want[i])
+		}
+	}
+	if stats.Package != stats.Domains[0].Power || stats.Core != stats.Domains[1].Power || stats.DRAM != stats.Domains[2].Power {
+		t.Errorf("got package %v, core %v and DRAM %v watts", stats.Package, stats.Core, stats.DRAM)
+	}
+}
+
+func TestCollectNoZones(t *testing.T) {
+	defer func(root string) { powercapRoot = root }(powercapRoot)
+	powercapRoot = copyPowercap(t, nil)
+	defer os.RemoveAll(powercapRoot)
+
+	if _, _, err := newCollector().collect(); err == nil {
+		t.Error("expected error without RAPL zones")
+	}
+}
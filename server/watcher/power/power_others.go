@@ -0,0 +1,16 @@
+// +build !linux
+
+package power
+
+import "fmt"
+
+// TODO: Windows/Darwin
+type collector struct{}
+
+func newCollector() *collector {
+	return &collector{}
+}
+
+func (c *collector) collect() (*Stats, float64, error) {
+	return nil, 0, fmt.Errorf("power: RAPL energy counters are only available on Linux")
+}
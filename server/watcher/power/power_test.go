@@ -0,0 +1,88 @@
+package power
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEnergyDelta(t *testing.T) {
+	tests := []struct {
+		last, cur, maxRange uint64
+		want                uint64
+	}{
+		{100, 250, 1000, 150},
+		{100, 100, 1000, 0},
+		{900, 50, 1000, 150},                           // Wrapped at max range
+		{262142328850, 2000000, 262143328850, 3000000}, // Wrapped at max_energy_range_uj of a package
+		{900, 50, 0, 50},                               // Unknown range
+		{262142328850, 2000000, 65712999613, 2000000},  // Range smaller than the last sample
+		{65712999613, 0, 65712999613, 0},               // Wrapped exactly at max range
+	}
+	for i, tt := range tests {
+		if got := energyDelta(tt.last, tt.cur, tt.maxRange); got != tt.want {
+			t.Errorf("%d: got %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+func TestLedger(t *testing.T) {
+	l := loadLedger("testdata/energy.json")
+	want := ledger{Day: "2021-03-31", DayEnergy: 500, Month: "2021-03", MonthEnergy: 12000}
+	if !reflect.DeepEqual(*l, want) {
+		t.Fatalf("got %+v, want %+v", *l, want)
+	}
+
+	tests := []struct {
+		now  time.Time
+		wh   float64
+		want ledger
+	}{
+		{time.Date(2021, 3, 31, 23, 59, 0, 0, time.Local), 10, ledger{"2021-03-31", 510, "2021-03", 12010}},
+		{time.Date(2021, 4, 1, 0, 1, 0, 0, time.Local), 5, ledger{"2021-04-01", 5, "2021-04", 5}}, // New month
+		{time.Date(2021, 4, 1, 12, 0, 0, 0, time.Local), 20, ledger{"2021-04-01", 25, "2021-04", 25}},
+		{time.Date(2021, 4, 2, 0, 0, 0, 0, time.Local), 1, ledger{"2021-04-02", 1, "2021-04", 26}}, // New day
+		{time.Date(2022, 4, 2, 0, 0, 0, 0, time.Local), 2, ledger{"2022-04-02", 2, "2022-04", 2}},  // Same day a year later
+	}
+	for i, tt := range tests {
+		l.add(tt.now, tt.wh)
+		if !reflect.DeepEqual(*l, tt.want) {
+			t.Errorf("%d: got %+v, want %+v", i, *l, tt.want)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "energy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "energy.json")
+	l.save(fp)
+	if got := loadLedger(fp); !reflect.DeepEqual(got, l) {
+		t.Errorf("got saved %+v, want %+v", *got, *l)
+	}
+}
+
+func TestLoadLedgerInvalid(t *testing.T) {
+	for _, fp := range []string{"testdata/energy-invalid.json", "testdata/missing.json"} {
+		if got := loadLedger(fp); !reflect.DeepEqual(*got, ledger{}) {
+			t.Errorf("%s: got %+v, want empty ledger", fp, *got)
+		}
+	}
+}
+
+func TestSaveEmptyLedger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "energy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "energy.json")
+	(&ledger{}).save(fp)
+	if _, err := os.Stat(fp); !os.IsNotExist(err) {
+		t.Errorf("got %v, want energy file isn't written before anything is counted", err)
+	}
+}
@@ -0,0 +1,198 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Probe types
+const (
+	HTTP = "http"
+	TCP  = "tcp"
+)
+
+// Timeout of targets which don't define their own timeout
+var DefaultTimeout = 5 * time.Second
+
+// Only the beginning of response bodies is matched
+const maxBodySize = 1 << 20
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	// Target is probed by HTTP GET if URL is set, otherwise by connecting to the TCP Address (host:port).
+	Target struct {
+		Name     string
+		URL      string
+		Address  string
+		Status   int    // Expected HTTP status code, any 2xx or 3xx if zero
+		Match    string // Regular expression HTTP response body must match
+		Insecure bool   // Skip TLS certificate verification
+		Timeout  time.Duration
+	}
+
+	Stats struct {
+		Targets []Result `json:"targets"`
+	}
+
+	Result struct {
+		Name     string  `json:"name"`
+		Type     string  `json:"type"`
+		Target   string  `json:"target"` // URL or address
+		Up       bool    `json:"up"`
+		Status   int     `json:"status,omitempty"` // HTTP status code
+		Latency  float64 `json:"latency"`          // Milliseconds to get the response or connect
+		Error    string  `json:"error,omitempty"`
+		Failures uint    `json:"failures"` // Consecutive failures
+	}
+
+	prober struct {
+		target   Target
+		match    *regexp.Regexp
+		matchErr error
+		client   *http.Client
+		failures uint
+	}
+
+	watcher struct {
+		targets []Target
+	}
+)
+
+func NewWatcher(targets []Target) Watcher {
+	return &watcher{targets: targets}
+}
+
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	go func() {
+		logrus.Infof("watcher: PROBE watcher started")
+		probers := make([]*prober, 0, len(w.targets))
+		for _, t := range w.targets {
+			probers = append(probers, newProber(t))
+		}
+		for {
+			select {
+			case <-ticker.C:
+				statsChan <- probeAll(ctx, probers)
+			case <-ctx.Done():
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: PROBE watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
+
+// probeAll probes all targets concurrently.
+func probeAll(ctx context.Context, probers []*prober) *Stats {
+	stats := &Stats{Targets: make([]Result, len(probers))}
+	var wg sync.WaitGroup
+	for i := range probers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			stats.Targets[i] = probers[i].probe(ctx)
+		}(i)
+	}
+	wg.Wait()
+	return stats
+}
+
+func newProber(t Target) *prober {
+	if t.Timeout <= 0 {
+		t.Timeout = DefaultTimeout
+	}
+	p := &prober{target: t}
+	if t.Match != "" {
+		p.match, p.matchErr = regexp.Compile(t.Match)
+	}
+	if t.URL != "" {
+		p.client = &http.Client{
+			Timeout: t.Timeout,
+			Transport: &http.Transport{
+				Proxy:             http.ProxyFromEnvironment,
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: t.Insecure},
+				DisableKeepAlives: true, // Each probe measures a new connection
+			},
+		}
+	}
+	return p
+}
+
+func (p *prober) probe(ctx context.Context) Result {
+	r := Result{Name: p.target.Name, Type: TCP, Target: p.target.Address}
+	if p.client != nil {
+		r.Type, r.Target = HTTP, p.target.URL
+	}
+	start := time.Now()
+	var err error
+	if r.Type == HTTP {
+		r.Status, err = p.probeHTTP(ctx)
+	} else {
+		err = p.probeTCP(ctx)
+	}
+	r.Latency = float64(time.Since(start).Nanoseconds()) / float64(time.Millisecond)
+	r.Up = err == nil
+	if err != nil {
+		r.Error = err.Error()
+		p.failures++
+	} else {
+		p.failures = 0
+	}
+	r.Failures = p.failures
+	return r
+}
+
+func (p *prober) probeHTTP(ctx context.Context) (int, error) {
+	if p.matchErr != nil {
+		return 0, fmt.Errorf("invalid match pattern: %s", p.matchErr)
+	}
+	req, err := http.NewRequest(http.MethodGet, p.target.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read response: %s", err)
+	}
+	if p.target.Status > 0 && resp.StatusCode != p.target.Status {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d, expected %d", resp.StatusCode, p.target.Status)
+	}
+	if p.target.Status <= 0 && (resp.StatusCode < 200 || resp.StatusCode >= 400) {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if p.match != nil && !p.match.Match(body) {
+		return resp.StatusCode, fmt.Errorf("response doesn't match %q", p.target.Match)
+	}
+	return resp.StatusCode, nil
+}
+
+func (p *prober) probeTCP(ctx context.Context) error {
+	d := net.Dialer{Timeout: p.target.Timeout}
+	conn, err := d.DialContext(ctx, "tcp", p.target.Address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,101 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProbeHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			fmt.Fprint(w, `{"status":"healthy"}`)
+		case "/redirect":
+			w.WriteHeader(http.StatusNotModified)
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		target Target
+		up     bool
+		status int
+	}{
+		{"ok", Target{URL: ts.URL + "/ok"}, true, 200},
+		{"3xx", Target{URL: ts.URL + "/redirect"}, true, 304},
+		{"4xx", Target{URL: ts.URL + "/missing"}, false, 404},
+		{"expected status", Target{URL: ts.URL + "/created", Status: 201}, true, 201},
+		{"unexpected status", Target{URL: ts.URL + "/ok", Status: 201}, false, 200},
+		{"body match", Target{URL: ts.URL + "/ok", Match: `"status":\s*"healthy"`}, true, 200},
+		{"body mismatch", Target{URL: ts.URL + "/ok", Match: "unhealthy"}, false, 200},
+		{"invalid match", Target{URL: ts.URL + "/ok", Match: "("}, false, 0},
+		{"timeout", Target{URL: ts.URL + "/slow", Timeout: 50 * time.Millisecond}, false, 0},
+	}
+	for _, tt := range tests {
+		r := newProber(tt.target).probe(context.Background())
+		if r.Type != HTTP || r.Up != tt.up || r.Status != tt.status {
+			t.Errorf("%s: got type %s, up %v, status %d (%s), want up %v, status %d",
+				tt.name, r.Type, r.Up, r.Status, r.Error, tt.up, tt.status)
+		}
+		if tt.up != (r.Error == "") {
+			t.Errorf("%s: unexpected error %q", tt.name, r.Error)
+		}
+	}
+}
+
+func TestProbeTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	if r := newProber(Target{Address: addr}).probe(context.Background()); !r.Up || r.Type != TCP {
+		t.Errorf("expected %s to be up, got %+v", addr, r)
+	}
+	l.Close()
+	if r := newProber(Target{Address: addr}).probe(context.Background()); r.Up {
+		t.Errorf("expected closed %s to be down, got %+v", addr, r)
+	}
+}
+
+func TestProbeFailures(t *testing.T) {
+	up := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	p := newProber(Target{URL: ts.URL})
+	steps := []struct {
+		up       bool
+		failures uint
+	}{
+		{true, 0},
+		{false, 1},
+		{false, 2},
+		{false, 3},
+		{true, 0}, // Recovered
+		{false, 1},
+	}
+	for i, s := range steps {
+		up = s.up
+		r := p.probe(context.Background())
+		if r.Up != s.up || r.Failures != s.failures {
+			t.Errorf("step %d: got up %v, failures %d, want up %v, failures %d", i, r.Up, r.Failures, s.up, s.failures)
+		}
+	}
+}
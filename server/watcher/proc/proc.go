@@ -0,0 +1,102 @@
+package proc
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const DefaultTop = 5
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	// Stats holds the top processes sorted by CPU, memory and I/O usage.
+	Stats struct {
+		ByCPU    []Process `json:"byCPU"`
+		ByMemory []Process `json:"byMemory"`
+		ByIO     []Process `json:"byIO"`
+	}
+
+	Process struct {
+		PID     int     `json:"pid"`
+		Name    string  `json:"name"`
+		User    string  `json:"user"`
+		CPU     float64 `json:"cpu"`     // Percent of one core, can be over 100 on multi-cores
+		Memory  uint64  `json:"memory"`  // Resident memory in MB
+		IORead  uint64  `json:"ioRead"`  // KB/s
+		IOWrite uint64  `json:"ioWrite"` // KB/s
+	}
+
+	watcher struct {
+		top int
+	}
+)
+
+// NewWatcher returns a process watcher which reports the top N processes.
+func NewWatcher(top int) Watcher {
+	if top <= 0 {
+		top = DefaultTop
+	}
+	return &watcher{top: top}
+}
+
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	go func() {
+		logrus.Infof("watcher: PROC watcher started")
+		c := newCollector()
+		for {
+			select {
+			case <-ticker.C:
+				procs, err := c.collect()
+				if err != nil {
+					logrus.Error(err)
+					continue
+				}
+				// CPU and I/O usages are cumulative so the first sample is only used to warm up
+				if procs != nil {
+					statsChan <- w.getStats(procs)
+				}
+			case <-ctx.Done():
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: PROC watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
+
+func (w *watcher) getStats(procs []Process) *Stats {
+	return &Stats{
+		ByCPU: topN(procs, w.top, func(a, b *Process) bool {
+			return a.CPU > b.CPU
+		}),
+		ByMemory: topN(procs, w.top, func(a, b *Process) bool {
+			return a.Memory > b.Memory
+		}),
+		ByIO: topN(procs, w.top, func(a, b *Process) bool {
+			return a.IORead+a.IOWrite > b.IORead+b.IOWrite
+		}),
+	}
+}
+
+// topN returns the first n processes sorted by the less function, the original slice is kept intact.
+func topN(procs []Process, n int, less func(a, b *Process) bool) []Process {
+	sorted := make([]Process, len(procs))
+	copy(sorted, procs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(&sorted[i], &sorted[j])
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
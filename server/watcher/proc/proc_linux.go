@@ -0,0 +1,164 @@
+// +build linux
+
+package proc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lnquy/nights-watch/server/util"
+)
+
+const clockTicks = 100 // USER_HZ, CPU times in /proc/[pid]/stat are counted in clock ticks
+
+var procRoot = "/proc"
+
+type (
+	// Raw cumulative counters of a process
+	sample struct {
+		startTime  uint64 // Used to detect reused PIDs
+		cpuTicks   uint64 // utime + stime
+		readBytes  uint64
+		writeBytes uint64
+	}
+
+	collector struct {
+		last     map[int]sample
+		lastTime time.Time
+		users    map[string]string // UID to username cache
+	}
+)
+
+func newCollector() *collector {
+	return &collector{users: make(map[string]string)}
+}
+
+// collect reads all processes and calculates their usages since the last sample.
+// Returns nil on the first sample.
+func (c *collector) collect() ([]Process, error) {
+	dirs, err := filepath.Glob(filepath.Join(procRoot, "[0-9]*"))
+	if err != nil {
+		return nil, fmt.Errorf("proc: failed to list processes: %s", err)
+	}
+	now := time.Now()
+	elapsed := now.Sub(c.lastTime).Seconds()
+	cur := make(map[int]sample, len(dirs))
+	procs := make([]Process, 0, len(dirs))
+	pageSize := uint64(os.Getpagesize())
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(filepath.Base(dir))
+		if err != nil {
+			continue
+		}
+		// Process may exit while being read, just skip it
+		b, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+		if err != nil {
+			continue
+		}
+		name, s, rss, err := parseStat(string(b))
+		if err != nil {
+			continue
+		}
+		s.readBytes, s.writeBytes = readIO(filepath.Join(dir, "io")) // Requires same user or root
+		cur[pid] = s
+
+		p := Process{
+			PID:    pid,
+			Name:   name,
+			User:   c.lookupUser(util.ReadUID(filepath.Join(dir, "status"))),
+			Memory: rss * pageSize / 1000000,
+		}
+		// Newly started process has no previous sample, report zero usage for now
+		if l, ok := c.last[pid]; ok && l.startTime == s.startTime && elapsed > 0 {
+			p.CPU = float64(util.Delta(l.cpuTicks, s.cpuTicks)) / clockTicks / elapsed * 100
+			p.IORead = uint64(float64(util.Delta(l.readBytes, s.readBytes)) / elapsed / 1000)
+			p.IOWrite = uint64(float64(util.Delta(l.writeBytes, s.writeBytes)) / elapsed / 1000)
+		}
+		procs = append(procs, p)
+	}
+
+	first := c.last == nil
+	c.last, c.lastTime = cur, now
+	if first {
+		return nil, nil
+	}
+	return procs, nil
+}
+
+func (c *collector) lookupUser(uid string) string {
+	if uid == "" {
+		return ""
+	}
+	if name, ok := c.users[uid]; ok {
+		return name
+	}
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	c.users[uid] = name
+	return name
+}
+
+// parseStat parses the content of /proc/[pid]/stat, e.g.:
+//
+//	1234 (my process) S 1 1234 1234 0 -1 4194560 1520 0 0 0 12 3 0 0 20 0 1 0 5025 12345678 1024 ...
+//
+// Process name can contain spaces and parentheses so it's taken between the first '(' and the last ')'.
+func parseStat(stat string) (name string, s sample, rss uint64, err error) {
+	start, end := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+	if start < 0 || end < start {
+		return "", s, 0, fmt.Errorf("proc: invalid stat: %q", stat)
+	}
+	name = stat[start+1 : end]
+	fields := strings.Fields(stat[end+1:]) // Starts from the 3rd field (state)
+	if len(fields) < 22 {
+		return "", s, 0, fmt.Errorf("proc: invalid stat: %q", stat)
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	s.cpuTicks = utime + stime
+	s.startTime, _ = strconv.ParseUint(fields[19], 10, 64)
+	rss, _ = strconv.ParseUint(fields[21], 10, 64) // Pages
+	return name, s, rss, nil
+}
+
+// readIO returns the bytes read from/written to storage by the process.
+func readIO(fp string) (read, write uint64) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+	return parseIO(f)
+}
+
+func parseIO(r io.Reader) (read, write uint64) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(strings.TrimSpace(kv[1]), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch kv[0] {
+		case "read_bytes":
+			read = v
+		case "write_bytes":
+			write = v
+		}
+	}
+	return read, write
+}
+
@@ -0,0 +1,174 @@
+// +build linux
+
+package proc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Fixtures of procfs in testdata/proc: postgres (1200), tmux whose name has spaces and parentheses (3400)
+// and sshd whose I/O counters are not readable (5600).
+const testProcRoot = "testdata/proc"
+
+func TestParseStat(t *testing.T) {
+	tests := []struct {
+		pid  string
+		name string
+		s    sample
+		rss  uint64
+	}{
+		{"1200", "postgres", sample{startTime: 5025, cpuTicks: 150}, 2560},
+		{"3400", "tmux: server) (1)", sample{startTime: 8000, cpuTicks: 600}, 1280},
+		{"5600", "sshd", sample{startTime: 9000, cpuTicks: 15}, 512},
+	}
+	for _, tt := range tests {
+		b, err := ioutil.ReadFile(filepath.Join(testProcRoot, tt.pid, "stat"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		name, s, rss, err := parseStat(string(b))
+		if err != nil {
+			t.Errorf("%s: %s", tt.pid, err)
+			continue
+		}
+		if name != tt.name || s != tt.s || rss != tt.rss {
+			t.Errorf("%s: got %q, %+v, %d, want %q, %+v, %d", tt.pid, name, s, rss, tt.name, tt.s, tt.rss)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"1200 postgres S 1",
+		"1200 )postgres( S 1 1200 1200 0 -1 4194560 1520 0 0 0 120 30 0 0 20 0 1 0 5025 12345678 2560",
+		"1200 (postgres) S 1 1200 1200 0 -1 4194560 1520 0 0 0 120 30", // Truncated
+	}
+	for _, stat := range invalid {
+		if name, s, _, err := parseStat(stat); err == nil {
+			t.Errorf("parseStat(%q) = %q, %+v, want error", stat, name, s)
+		}
+	}
+}
+
+func TestReadIO(t *testing.T) {
+	tests := []struct {
+		pid         string
+		read, write uint64
+	}{
+		{"1200", 1000000, 500000},
+		{"3400", 0, 8192},
+		{"5600", 0, 0}, // Not readable
+	}
+	for _, tt := range tests {
+		if read, write := readIO(filepath.Join(testProcRoot, tt.pid, "io")); read != tt.read || write != tt.write {
+			t.Errorf("%s: got %d, %d, want %d, %d", tt.pid, read, write, tt.read, tt.write)
+		}
+	}
+	if read, write := parseIO(strings.NewReader("read_bytes: abc\nwrite_bytes\nwrite_bytes: 42\n")); read != 0 || write != 42 {
+		t.Errorf("got %d, %d, want 0, 42", read, write)
+	}
+}
+
+// copyProc copies the procfs fixtures to a temporary directory which can be changed between samples.
+func copyProc(t *testing.T) string {
+	root, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs, err := ioutil.ReadDir(testProcRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range dirs {
+		if err := os.Mkdir(filepath.Join(root, d.Name()), 0755); err != nil {
+			t.Fatal(err)
+		}
+		files, err := ioutil.ReadDir(filepath.Join(testProcRoot, d.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			writeProcFile(t, root, d.Name()+"/"+f.Name(), readProcFile(t, testProcRoot, d.Name()+"/"+f.Name()))
+		}
+	}
+	return root
+}
+
+func readProcFile(t *testing.T, root, name string) string {
+	b, err := ioutil.ReadFile(filepath.Join(root, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func writeProcFile(t *testing.T, root, name, content string) {
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	defer func(root string) { procRoot = root }(procRoot)
+	procRoot = copyProc(t)
+	defer os.RemoveAll(procRoot)
+
+	c := newCollector()
+	if procs, err := c.collect(); err != nil || procs != nil {
+		t.Fatalf("got %+v (error: %v) on first sample, want nil", procs, err)
+	}
+
+	// postgres used 50 ticks and did I/O, tmux exited and its PID is reused by a process which has used more ticks,
+	// sshd used 10 ticks, dbus is started
+	replace := func(name, old, new string) {
+		writeProcFile(t, procRoot, name, strings.Replace(readProcFile(t, procRoot, name), old, new, 1))
+	}
+	replace("1200/stat", " 120 30 ", " 160 40 ")
+	replace("1200/io", "read_bytes: 1000000", "read_bytes: 3000000")
+	replace("1200/io", "write_bytes: 500000", "write_bytes: 1500000")
+	replace("3400/stat", " 500 100 0 0 20 0 2 0 8000 ", " 900 100 0 0 20 0 2 0 9500 ")
+	replace("5600/stat", " 10 5 ", " 20 5 ")
+	writeProcFile(t, procRoot, "7800/stat", "7800 (dbus-daemon) S 1 7800 7800 0 -1 4194560 300 0 0 0 100 50 0 0 20 0 1 0 9900 12345678 256 0 0 0 0\n")
+	c.lastTime = c.lastTime.Add(-time.Second)
+
+	procs, err := c.collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageSize := uint64(os.Getpagesize())
+	want := map[int]Process{
+		1200: {PID: 1200, Name: "postgres", User: "root", CPU: 50, Memory: 2560 * pageSize / 1000000, IORead: 2000, IOWrite: 1000},
+		3400: {PID: 3400, Name: "tmux: server) (1)", User: "root", Memory: 1280 * pageSize / 1000000}, // PID reused
+		5600: {PID: 5600, Name: "sshd", User: "root", CPU: 10, Memory: 512 * pageSize / 1000000},
+		7800: {PID: 7800, Name: "dbus-daemon", Memory: 256 * pageSize / 1000000}, // No previous sample nor status
+	}
+	if len(procs) != len(want) {
+		t.Fatalf("got %d processes, want %d", len(procs), len(want))
+	}
+	// Elapsed time is slightly longer than 1s
+	near := func(got, want float64) bool {
+		return got <= want && got >= want*0.9
+	}
+	for _, p := range procs {
+		w, ok := want[p.PID]
+		if !ok {
+			t.Errorf("unexpected process %+v", p)
+			continue
+		}
+		if p.Name != w.Name || p.Memory != w.Memory || !near(p.CPU, w.CPU) ||
+			!near(float64(p.IORead), float64(w.IORead)) || !near(float64(p.IOWrite), float64(w.IOWrite)) {
+			t.Errorf("%d: got %+v, want %+v", p.PID, p, w)
+		}
+		// UID is reported if the user can't be looked up
+		if p.User != w.User && !(w.User == "root" && p.User == "0") {
+			t.Errorf("%d: got user %q, want %q", p.PID, p.User, w.User)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// +build !linux
+
+package proc
+
+// TODO: Windows/Darwin
+type collector struct{}
+
+func newCollector() *collector {
+	return &collector{}
+}
+
+func (c *collector) collect() ([]Process, error) {
+	return []Process{}, nil
+}
@@ -0,0 +1,166 @@
+package prometheus
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sample is a sample of a series in Prometheus text exposition format.
+type sample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// key identifies the series of the sample, i.e.: name and labels sorted by name.
+func (s sample) key() string {
+	names := make([]string, 0, len(s.labels))
+	for n := range s.labels {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	b := bytes.Buffer{}
+	b.WriteString(s.name)
+	for _, n := range names {
+		b.WriteString("," + n + "=" + s.labels[n])
+	}
+	return b.String()
+}
+
+// parseText parses the samples of Prometheus text exposition format, e.g.:
+//
+//	# HELP http_requests_total The total number of HTTP requests.
+//	# TYPE http_requests_total counter
+//	http_requests_total{method="post",code="200"} 1027 1395066363000
+//	process_open_fds 15
+//
+// Comments and timestamps are ignored.
+func parseText(r io.Reader) ([]sample, error) {
+	samples := make([]sample, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		s, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, s)
+	}
+	return samples, scanner.Err()
+}
+
+func parseLine(line string) (sample, error) {
+	s := sample{labels: make(map[string]string)}
+	i := strings.IndexAny(line, "{ \t")
+	if i <= 0 {
+		return s, fmt.Errorf("invalid sample: %q", line)
+	}
+	s.name = line[:i]
+	rest := line[i:]
+	if rest[0] == '{' {
+		labels, r, err := parseLabels(rest[1:])
+		if err != nil {
+			return s, fmt.Errorf("invalid sample: %q: %s", line, err)
+		}
+		s.labels, rest = make(map[string]string, len(labels)), r
+		for _, l := range labels {
+			s.labels[l.name] = l.value
+		}
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return s, fmt.Errorf("invalid sample: missing value: %q", line)
+	}
+	v, err := parseValue(fields[0])
+	if err != nil {
+		return s, fmt.Errorf("invalid sample value: %q", fields[0])
+	}
+	s.value = v
+	return s, nil
+}
+
+type label struct {
+	name  string
+	op    string // Only used by selectors
+	value string
+}
+
+// parseLabels parses the labels after '{' until '}', e.g.: method="post",code="200"}
+// Returns the labels and the rest after '}'. Selectors also allow !=, =~ and !~ operators.
+func parseLabels(s string) ([]label, string, error) {
+	labels := make([]label, 0)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return nil, "", fmt.Errorf("missing '}'")
+		}
+		if s[0] == '}' {
+			return labels, s[1:], nil
+		}
+		i := strings.IndexAny(s, "=!~")
+		if i <= 0 {
+			return nil, "", fmt.Errorf("invalid label: %q", s)
+		}
+		l := label{name: strings.TrimSpace(s[:i])}
+		s = s[i:]
+		for _, op := range []string{"=~", "!~", "!=", "="} {
+			if strings.HasPrefix(s, op) {
+				l.op, s = op, s[len(op):]
+				break
+			}
+		}
+		s = strings.TrimLeft(s, " \t")
+		if l.op == "" || s == "" || s[0] != '"' {
+			return nil, "", fmt.Errorf("invalid label: %q", s)
+		}
+		var err error
+		if l.value, s, err = unquote(s[1:]); err != nil {
+			return nil, "", err
+		}
+		labels = append(labels, l)
+	}
+}
+
+// unquote returns the escaped string until the closing quote and the rest after it.
+func unquote(s string) (string, string, error) {
+	b := bytes.Buffer{}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return b.String(), s[i+1:], nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated label value")
+}
+
+func parseValue(s string) (float64, error) {
+	switch s {
+	case "+Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
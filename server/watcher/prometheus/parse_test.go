@@ -0,0 +1,127 @@
+package prometheus
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		name   string
+		labels map[string]string
+		value  float64
+	}{
+		{`process_open_fds 15`, "process_open_fds", map[string]string{}, 15},
+		{`http_requests_total{method="post",code="200"} 1027 1395066363000`, "http_requests_total",
+			map[string]string{"method": "post", "code": "200"}, 1027},
+		{`http_requests_total{ method = "get" , } 3`, "http_requests_total", map[string]string{"method": "get"}, 3},
+		{`empty_labels{} 1.5e3`, "empty_labels", map[string]string{}, 1500},
+		{`msg{path="C:\\dir\\",text="say \"hi\"\nbye"} -2`, "msg",
+			map[string]string{"path": `C:\dir\`, "text": "say \"hi\"\nbye"}, -2},
+		{`brace{text="a}b,c=d"} 1`, "brace", map[string]string{"text": "a}b,c=d"}, 1},
+		{`inf +Inf`, "inf", map[string]string{}, math.Inf(1)},
+		{`neg_inf -Inf`, "neg_inf", map[string]string{}, math.Inf(-1)},
+	}
+	for _, tt := range tests {
+		s, err := parseLine(tt.line)
+		if err != nil {
+			t.Errorf("parseLine(%q): %s", tt.line, err)
+			continue
+		}
+		if s.name != tt.name || !reflect.DeepEqual(s.labels, tt.labels) || s.value != tt.value {
+			t.Errorf("parseLine(%q) = %+v, want name %s, labels %v, value %v", tt.line, s, tt.name, tt.labels, tt.value)
+		}
+	}
+
+	s, err := parseLine("nan NaN")
+	if err != nil || !math.IsNaN(s.value) {
+		t.Errorf("parseLine(NaN) = %v, %v", s.value, err)
+	}
+
+	invalid := []string{
+		`{code="200"} 1`,
+		`missing_value`,
+		`missing_value{code="200"}`,
+		`unclosed{code="200" 1`,
+		`unquoted{code=200} 1`,
+		`unterminated{code="200} 1`,
+		`bad_value abc`,
+		`bad_op{code~"200"} 1`,
+	}
+	for _, line := range invalid {
+		if s, err := parseLine(line); err == nil {
+			t.Errorf("parseLine(%q) = %+v, want error", line, s)
+		}
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, rest, err := parseLabels(`code=~"5..", method!="get",path!~"/api/.*",job="node"} 12`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []label{
+		{name: "code", op: "=~", value: "5.."},
+		{name: "method", op: "!=", value: "get"},
+		{name: "path", op: "!~", value: "/api/.*"},
+		{name: "job", op: "=", value: "node"},
+	}
+	if !reflect.DeepEqual(labels, want) || rest != " 12" {
+		t.Errorf("parseLabels = %+v, %q, want %+v, %q", labels, rest, want, " 12")
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		s, value, rest string
+	}{
+		{`abc" 1`, "abc", " 1"},
+		{`" 1`, "", " 1"},
+		{`a\"b"}`, `a"b`, "}"},
+		{`a\\b"`, `a\b`, ""},
+		{`a\nb"`, "a\nb", ""},
+		{`a\tb"`, "atb", ""}, // Unknown escapes keep the character
+	}
+	for _, tt := range tests {
+		value, rest, err := unquote(tt.s)
+		if err != nil || value != tt.value || rest != tt.rest {
+			t.Errorf("unquote(%q) = %q, %q, %v, want %q, %q", tt.s, value, rest, err, tt.value, tt.rest)
+		}
+	}
+	for _, s := range []string{`abc`, `abc\"`, `abc\`} {
+		if _, _, err := unquote(s); err == nil {
+			t.Errorf("unquote(%q): expected error", s)
+		}
+	}
+}
+
+func TestParseText(t *testing.T) {
+	text := `# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+
+  http_requests_total{method="post",code="400"}    3
+process_open_fds 15
+`
+	samples, err := parseText(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 3 || samples[1].labels["code"] != "400" || samples[1].value != 3 || samples[2].name != "process_open_fds" {
+		t.Errorf("unexpected samples: %+v", samples)
+	}
+	if _, err := parseText(strings.NewReader("ok 1\nbroken\n")); err == nil {
+		t.Error("expected error on invalid line")
+	}
+}
+
+func TestSampleKey(t *testing.T) {
+	a, _ := parseLine(`x{b="2",a="1"} 1`)
+	b, _ := parseLine(`x{a="1",b="2"} 5`)
+	if a.key() != b.key() || a.key() != "x,a=1,b=2" {
+		t.Errorf("keys differ: %q, %q", a.key(), b.key())
+	}
+}
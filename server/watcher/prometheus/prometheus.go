@@ -0,0 +1,230 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lnquy/nights-watch/server/watcher/custom"
+	"github.com/sirupsen/logrus"
+)
+
+// Timeout of targets which don't define their own timeout
+var DefaultTimeout = 5 * time.Second
+
+// Responses larger than this are rejected
+const maxBodySize = 10 << 20
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	// Target is a Prometheus text format endpoint, e.g.: http://localhost:8080/metrics
+	Target struct {
+		Name    string
+		URL     string
+		Timeout time.Duration
+		Series  []Series
+	}
+
+	// Series selects the series to report as <target name>.<series name> metric.
+	// Values of all series matched the selector are summed.
+	Series struct {
+		Name     string
+		Selector string // E.g.: http_requests_total{code=~"5..",method!="get"}
+		Rate     bool   // Report per second rate of a counter instead of its value
+	}
+
+	Stats struct {
+		Metrics []custom.Metric `json:"metrics"`
+		Targets []Status        `json:"targets"`
+	}
+
+	Status struct {
+		Name     string  `json:"name"`
+		URL      string  `json:"url"`
+		Up       bool    `json:"up"`
+		Duration float64 `json:"duration"` // Milliseconds to scrape
+		Samples  int     `json:"samples"`
+		Error    string  `json:"error,omitempty"`
+	}
+
+	watcher struct {
+		targets []Target
+	}
+)
+
+func NewWatcher(targets []Target) Watcher {
+	return &watcher{targets: targets}
+}
+
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	go func() {
+		logrus.Infof("watcher: PROMETHEUS watcher started")
+		scrapers := make([]*scraper, 0, len(w.targets))
+		for _, t := range w.targets {
+			scrapers = append(scrapers, newScraper(t))
+		}
+		for {
+			select {
+			case <-ticker.C:
+				statsChan <- scrapeAll(ctx, scrapers)
+			case <-ctx.Done():
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: PROMETHEUS watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
+
+// scrapeAll scrapes all targets concurrently.
+func scrapeAll(ctx context.Context, scrapers []*scraper) *Stats {
+	statuses := make([]Status, len(scrapers))
+	metrics := make([][]custom.Metric, len(scrapers))
+	var wg sync.WaitGroup
+	for i := range scrapers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i], metrics[i] = scrapers[i].scrape(ctx, time.Now())
+		}(i)
+	}
+	wg.Wait()
+
+	stats := &Stats{Metrics: make([]custom.Metric, 0), Targets: statuses}
+	for i, s := range statuses {
+		if s.Error != "" {
+			logrus.Errorf("prometheus: %s: %s", s.Name, s.Error)
+		}
+		stats.Metrics = append(stats.Metrics, metrics[i]...)
+	}
+	return stats
+}
+
+type scraper struct {
+	target    Target
+	client    *http.Client
+	selectors []*selector
+	errs      []error // Selector errors
+
+	last     map[string]float64 // Last values of counters to calculate rates, by series key
+	lastTime time.Time
+}
+
+func newScraper(t Target) *scraper {
+	if t.Timeout <= 0 {
+		t.Timeout = DefaultTimeout
+	}
+	s := &scraper{
+		target: t,
+		client: &http.Client{Timeout: t.Timeout},
+	}
+	for _, series := range t.Series {
+		sel, err := parseSelector(series.Selector)
+		if err != nil {
+			logrus.Errorf("prometheus: %s: %s", t.Name, err)
+		}
+		s.selectors = append(s.selectors, sel)
+		s.errs = append(s.errs, err)
+	}
+	return s
+}
+
+func (s *scraper) scrape(ctx context.Context, now time.Time) (Status, []custom.Metric) {
+	status := Status{Name: s.target.Name, URL: s.target.URL}
+	samples, err := s.fetch(ctx)
+	status.Duration = float64(time.Since(now).Nanoseconds()) / float64(time.Millisecond)
+	if err != nil {
+		status.Error = err.Error()
+		return status, nil
+	}
+	status.Up, status.Samples = true, len(samples)
+
+	elapsed := now.Sub(s.lastTime).Seconds()
+	cur := make(map[string]float64)
+	metrics := make([]custom.Metric, 0, len(s.target.Series))
+	for i, series := range s.target.Series {
+		if s.errs[i] != nil {
+			status.Error = s.errs[i].Error()
+			continue
+		}
+		var value float64
+		matched, rated := false, false
+		for _, sm := range samples {
+			if !s.selectors[i].matches(sm) {
+				continue
+			}
+			// NaN and Inf samples (e.g.: quantiles of an empty summary) can't be reported in JSON
+			if math.IsNaN(sm.value) || math.IsInf(sm.value, 0) {
+				continue
+			}
+			matched = true
+			if !series.Rate {
+				value += sm.value
+				continue
+			}
+			key := sm.key()
+			cur[key] = sm.value
+			last, ok := s.last[key]
+			if !ok || elapsed <= 0 {
+				continue
+			}
+			rated = true
+			if sm.value >= last {
+				value += (sm.value - last) / elapsed
+			} else { // Counter has been reset
+				value += sm.value / elapsed
+			}
+		}
+		if !matched || (series.Rate && !rated) { // Rate is only available from the second scrape
+			continue
+		}
+		if math.IsInf(value, 0) {
+			status.Error = fmt.Sprintf("value of series %s is out of range", series.Name)
+			continue
+		}
+		metrics = append(metrics, custom.Metric{
+			Name:    s.target.Name + "." + series.Name,
+			Command: s.target.Name,
+			Value:   value,
+		})
+	}
+	s.last, s.lastTime = cur, now
+	return status, metrics
+}
+
+func (s *scraper) fetch(ctx context.Context) ([]sample, error) {
+	req, err := http.NewRequest(http.MethodGet, s.target.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %s", err)
+	}
+	if len(body) > maxBodySize {
+		return nil, fmt.Errorf("response is larger than %d bytes", maxBodySize)
+	}
+	return parseText(bytes.NewReader(body))
+}
@@ -0,0 +1,153 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScrape(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large" {
+			fmt.Fprint(w, strings.Repeat("# padding\n", maxBodySize/10+1))
+			return
+		}
+		if body == "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	s := newScraper(Target{
+		Name: "app",
+		URL:  ts.URL,
+		Series: []Series{
+			{Name: "errors", Selector: `http_requests_total{code=~"5.."}`, Rate: true},
+			{Name: "fds", Selector: "process_open_fds"},
+			{Name: "missing", Selector: "missing_total"},
+		},
+	})
+	steps := []struct {
+		body   string
+		errors float64 // Negative if the rate is not available
+		fds    float64
+	}{
+		{`http_requests_total{code="500"} 100
+http_requests_total{code="503"} 10
+http_requests_total{code="200"} 5000
+process_open_fds 15
+`, -1, 15},
+		{`http_requests_total{code="500"} 150
+http_requests_total{code="503"} 20
+http_requests_total{code="200"} 9000
+process_open_fds 17
+`, 6, 17}, // (50 + 10) / 10s
+		{`http_requests_total{code="500"} 30
+http_requests_total{code="503"} 20
+process_open_fds 16
+`, 3, 16}, // 500 has been reset: 30 / 10s
+		{`http_requests_total{code="500"} 40
+http_requests_total{code="503"} 20
+http_requests_total{code="504"} 7
+process_open_fds 16
+`, 1, 16}, // 504 is new and only rated from the next scrape
+	}
+	now := time.Now()
+	for i, step := range steps {
+		body = step.body
+		status, metrics := s.scrape(context.Background(), now.Add(time.Duration(i)*10*time.Second))
+		if !status.Up || status.Error != "" {
+			t.Fatalf("step %d: unexpected status %+v", i, status)
+		}
+		got := make(map[string]float64)
+		for _, m := range metrics {
+			got[m.Name] = m.Value
+		}
+		if v, ok := got["app.errors"]; (step.errors < 0) == ok || (ok && v != step.errors) {
+			t.Errorf("step %d: errors = %v (%v), want %v", i, v, ok, step.errors)
+		}
+		if got["app.fds"] != step.fds {
+			t.Errorf("step %d: fds = %v, want %v", i, got["app.fds"], step.fds)
+		}
+		if _, ok := got["app.missing"]; ok {
+			t.Errorf("step %d: unmatched series must not be reported", i)
+		}
+	}
+
+	body = ""
+	if status, metrics := s.scrape(context.Background(), time.Now()); status.Up || status.Error == "" || len(metrics) > 0 {
+		t.Errorf("expected failed scrape, got %+v, %v", status, metrics)
+	}
+
+	large := newScraper(Target{Name: "large", URL: ts.URL + "/large"})
+	if status, _ := large.scrape(context.Background(), time.Now()); status.Up || !strings.Contains(status.Error, "larger") {
+		t.Errorf("expected too large response error, got %+v", status)
+	}
+}
+
+func TestScrapeInvalidSelector(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "up 1\n")
+	}))
+	defer ts.Close()
+
+	s := newScraper(Target{Name: "app", URL: ts.URL, Series: []Series{
+		{Name: "bad", Selector: `up{job=~"("}`},
+		{Name: "up", Selector: "up"},
+	}})
+	status, metrics := s.scrape(context.Background(), time.Now())
+	if !status.Up || status.Error == "" {
+		t.Errorf("expected selector error, got %+v", status)
+	}
+	if len(metrics) != 1 || metrics[0].Name != "app.up" || metrics[0].Value != 1 {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+}
+
+func TestScrapeNonFinite(t *testing.T) {
+	body := `rpc_duration_seconds{quantile="0.5"} NaN
+rpc_duration_seconds{quantile="0.9"} 0.25
+le_bucket{le="+Inf"} +Inf
+neg -Inf
+huge 1.7e308
+huge 1.7e308
+`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	s := newScraper(Target{Name: "app", URL: ts.URL, Series: []Series{
+		{Name: "duration", Selector: "rpc_duration_seconds"},
+		{Name: "bucket", Selector: "le_bucket"},
+		{Name: "neg", Selector: "neg"},
+		{Name: "huge", Selector: "huge"},
+	}})
+	status, metrics := s.scrape(context.Background(), time.Now())
+	if !status.Up || !strings.Contains(status.Error, "huge") {
+		t.Errorf("expected out of range error of huge series, got %+v", status)
+	}
+	// NaN and Inf samples are skipped, series of only non-finite samples aren't reported
+	if len(metrics) != 1 || metrics[0].Name != "app.duration" || metrics[0].Value != 0.25 {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+	if _, err := json.Marshal(metrics); err != nil {
+		t.Errorf("failed to marshal metrics: %s", err)
+	}
+
+	// Rates of non-finite samples are skipped too
+	rate := newScraper(Target{Name: "app", URL: ts.URL, Series: []Series{{Name: "bucket", Selector: "le_bucket", Rate: true}}})
+	now := time.Now()
+	rate.scrape(context.Background(), now)
+	if _, metrics := rate.scrape(context.Background(), now.Add(time.Second)); len(metrics) > 0 {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+}
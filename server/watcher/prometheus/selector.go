@@ -0,0 +1,79 @@
+package prometheus
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// selector selects series by metric name and label matchers, e.g.: http_requests_total{code=~"5..",method!="get"}
+type selector struct {
+	name     string
+	matchers []matcher
+}
+
+type matcher struct {
+	label
+	regex *regexp.Regexp // For =~ and !~
+}
+
+func parseSelector(s string) (*selector, error) {
+	s = strings.TrimSpace(s)
+	sel := &selector{name: s}
+	i := strings.IndexByte(s, '{')
+	if s == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+	if i < 0 {
+		return sel, nil
+	}
+	sel.name = strings.TrimSpace(s[:i])
+	labels, rest, err := parseLabels(s[i+1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %s", s, err)
+	}
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("invalid selector %q: unexpected %q", s, rest)
+	}
+	for _, l := range labels {
+		m := matcher{label: l}
+		if l.op == "=~" || l.op == "!~" {
+			// Regular expressions are fully anchored like Prometheus
+			if m.regex, err = regexp.Compile("^(?:" + l.value + ")$"); err != nil {
+				return nil, fmt.Errorf("invalid selector %q: %s", s, err)
+			}
+		}
+		sel.matchers = append(sel.matchers, m)
+	}
+	if sel.name == "" && len(sel.matchers) == 0 {
+		return nil, fmt.Errorf("invalid selector %q: empty selector", s)
+	}
+	return sel, nil
+}
+
+func (sel *selector) matches(s sample) bool {
+	if sel.name != "" && sel.name != s.name {
+		return false
+	}
+	for _, m := range sel.matchers {
+		v := s.labels[m.name] // Missing label is the same as empty value
+		if m.name == "__name__" {
+			v = s.name
+		}
+		var ok bool
+		switch m.op {
+		case "=":
+			ok = v == m.value
+		case "!=":
+			ok = v != m.value
+		case "=~":
+			ok = m.regex.MatchString(v)
+		case "!~":
+			ok = !m.regex.MatchString(v)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package prometheus
+
+import "testing"
+
+func TestParseSelector(t *testing.T) {
+	samples := map[string]sample{
+		"up":         {name: "up", labels: map[string]string{"job": "node"}},
+		"get200":     {name: "http_requests_total", labels: map[string]string{"method": "get", "code": "200"}},
+		"post500":    {name: "http_requests_total", labels: map[string]string{"method": "post", "code": "500"}},
+		"post5000":   {name: "http_requests_total", labels: map[string]string{"method": "post", "code": "5000"}},
+		"post1500":   {name: "http_requests_total", labels: map[string]string{"method": "post", "code": "1500"}},
+		"nomethod":   {name: "http_requests_total", labels: map[string]string{"code": "503"}},
+		"other":      {name: "errors_total", labels: map[string]string{"code": "500"}},
+		"emptylabel": {name: "errors_total", labels: map[string]string{"code": ""}},
+	}
+	tests := []struct {
+		selector string
+		matches  []string
+	}{
+		{"up", []string{"up"}},
+		{` up { } `, []string{"up"}},
+		{`http_requests_total{code="200"}`, []string{"get200"}},
+		// Regular expressions are anchored, so 5.. doesn't match 5000 or 1500
+		{`http_requests_total{code=~"5.."}`, []string{"post500", "nomethod"}},
+		{`http_requests_total{code!~"5.."}`, []string{"get200", "post5000", "post1500"}},
+		{`http_requests_total{code=~"5..|2.."}`, []string{"get200", "post500", "nomethod"}},
+		// Missing label is the same as empty value
+		{`http_requests_total{method!="get"}`, []string{"post500", "post5000", "post1500", "nomethod"}},
+		{`http_requests_total{method=""}`, []string{"nomethod"}},
+		{`{code="500"}`, []string{"post500", "other"}},
+		{`{__name__="errors_total"}`, []string{"other", "emptylabel"}},
+		{`{__name__=~"errors_.*",code=~".+"}`, []string{"other"}},
+	}
+	for _, tt := range tests {
+		sel, err := parseSelector(tt.selector)
+		if err != nil {
+			t.Errorf("parseSelector(%q): %s", tt.selector, err)
+			continue
+		}
+		want := make(map[string]bool)
+		for _, m := range tt.matches {
+			want[m] = true
+		}
+		for name, s := range samples {
+			if got := sel.matches(s); got != want[name] {
+				t.Errorf("%s matches %s = %v, want %v", tt.selector, name, got, want[name])
+			}
+		}
+	}
+
+	invalid := []string{
+		``,
+		`{}`,
+		`x{code=~"("}`,
+		`x{code="200"} extra`,
+		`x{code="200"`,
+		`x{code=200}`,
+	}
+	for _, s := range invalid {
+		if _, err := parseSelector(s); err == nil {
+			t.Errorf("parseSelector(%q): expected error", s)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package psi
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	// Stats holds the Pressure Stall Information of CPU, memory and I/O.
+	// If PSI is not supported by the kernel, only the reason is reported.
+	Stats struct {
+		Available bool     `json:"available"`
+		Reason    string   `json:"reason,omitempty"`
+		CPU       Pressure `json:"cpu"`
+		Memory    Pressure `json:"memory"`
+		IO        Pressure `json:"io"`
+	}
+
+	// Pressure holds the share of time in which some (at least one) or all non-idle tasks
+	// were stalled on a resource. CPU full pressure is only reported by kernel 5.13+.
+	Pressure struct {
+		Some Stall `json:"some"`
+		Full Stall `json:"full"`
+	}
+
+	Stall struct {
+		Avg10   float64 `json:"avg10"`   // Percent
+		Avg60   float64 `json:"avg60"`   // Percent
+		Avg300  float64 `json:"avg300"`  // Percent
+		Total   uint64  `json:"total"`   // Total stall time in µs
+		Current float64 `json:"current"` // Percent of stall time since the last sample
+	}
+
+	watcher struct{}
+)
+
+func NewWatcher() Watcher {
+	return &watcher{}
+}
+
+// GetStats periodically get PSI statistics from system and returns that value to a Stats channel.
+// PSI is detected once when starting watcher, if it's not available (e.g.: kernel older than 4.20 or booted
+// with psi=0), an unavailable Stats is returned and the watcher stops sampling.
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	go func() {
+		logrus.Infof("watcher: PSI watcher started")
+		c := newCollector()
+		if stats, err := c.collect(); err != nil {
+			logrus.Warnf("psi: PSI watcher is unavailable: %s", err)
+			ticker.Stop()
+			statsChan <- &Stats{Reason: err.Error()}
+		} else {
+			statsChan <- stats
+		}
+		for {
+			select {
+			case <-ticker.C: // Never ticks if PSI is unavailable
+				stats, err := c.collect()
+				if err != nil {
+					logrus.Error(err)
+					continue
+				}
+				statsChan <- stats
+			case <-ctx.Done():
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: PSI watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
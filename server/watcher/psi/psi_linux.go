@@ -0,0 +1,119 @@
+// +build linux
+
+package psi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var pressureRoot = "/proc/pressure" // Requires kernel 4.20+ with CONFIG_PSI enabled
+
+type collector struct {
+	last     *Stats
+	lastTime time.Time
+}
+
+func newCollector() *collector {
+	return &collector{}
+}
+
+func (c *collector) collect() (*Stats, error) {
+	stats := &Stats{Available: true}
+	for _, r := range []struct {
+		name string
+		p    *Pressure
+	}{
+		{"cpu", &stats.CPU},
+		{"memory", &stats.Memory},
+		{"io", &stats.IO},
+	} {
+		f, err := os.Open(filepath.Join(pressureRoot, r.name))
+		if err != nil {
+			return nil, fmt.Errorf("psi: failed to read %s pressure: %s", r.name, err)
+		}
+		*r.p, err = parsePressure(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("psi: failed to parse %s pressure: %s", r.name, err)
+		}
+	}
+
+	// Total stall time is cumulative so the first sample has no current stall
+	now := time.Now()
+	if c.last != nil {
+		elapsed := float64(now.Sub(c.lastTime).Nanoseconds()) / 1000 // µs
+		stats.CPU.current(&c.last.CPU, elapsed)
+		stats.Memory.current(&c.last.Memory, elapsed)
+		stats.IO.current(&c.last.IO, elapsed)
+	}
+	c.last, c.lastTime = stats, now
+	return stats, nil
+}
+
+func (p *Pressure) current(last *Pressure, elapsed float64) {
+	p.Some.Current = stallPercent(last.Some.Total, p.Some.Total, elapsed)
+	p.Full.Current = stallPercent(last.Full.Total, p.Full.Total, elapsed)
+}
+
+func stallPercent(last, cur uint64, elapsed float64) float64 {
+	if elapsed <= 0 || cur < last {
+		return 0
+	}
+	v := float64(cur-last) / elapsed * 100
+	if v > 100 {
+		v = 100
+	}
+	return v
+}
+
+// parsePressure parses the content of a PSI file, e.g.:
+//
+//	some avg10=1.53 avg60=0.87 avg300=0.28 total=19836523
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func parsePressure(r io.Reader) (Pressure, error) {
+	p := Pressure{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var s *Stall
+		switch fields[0] {
+		case "some":
+			s = &p.Some
+		case "full":
+			s = &p.Full
+		default:
+			continue
+		}
+		for _, f := range fields[1:] {
+			kv := strings.SplitN(f, "=", 2)
+			if len(kv) != 2 {
+				return p, fmt.Errorf("invalid field: %q", f)
+			}
+			var err error
+			switch kv[0] {
+			case "avg10":
+				s.Avg10, err = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				s.Avg60, err = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				s.Avg300, err = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				s.Total, err = strconv.ParseUint(kv[1], 10, 64)
+			}
+			if err != nil {
+				return p, fmt.Errorf("invalid field: %q", f)
+			}
+		}
+	}
+	return p, scanner.Err()
+}
@@ -0,0 +1,131 @@
+// +build linux
+
+package psi
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePressure(t *testing.T) {
+	tests := []struct {
+		content string
+		want    Pressure
+		err     bool
+	}{
+		{
+			"some avg10=1.53 avg60=0.87 avg300=0.28 total=19836523\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+			Pressure{Some: Stall{Avg10: 1.53, Avg60: 0.87, Avg300: 0.28, Total: 19836523}}, false,
+		},
+		{ // CPU pressure before kernel 5.13 has no full line
+			"some avg10=0.00 avg60=0.05 avg300=0.10 total=42\n",
+			Pressure{Some: Stall{Avg60: 0.05, Avg300: 0.1, Total: 42}}, false,
+		},
+		{
+			"some avg10=12.40 avg60=8.02 avg300=2.55 total=2834711\nfull avg10=6.10 avg60=3.97 avg300=1.20 total=1203377\n",
+			Pressure{Some: Stall{Avg10: 12.4, Avg60: 8.02, Avg300: 2.55, Total: 2834711}, Full: Stall{Avg10: 6.1, Avg60: 3.97, Avg300: 1.2, Total: 1203377}}, false,
+		},
+		{"", Pressure{}, false},
+		{"some avg10 avg60=0.87\n", Pressure{}, true},
+		{"some avg10=high\n", Pressure{}, true},
+		{"some total=-1\n", Pressure{}, true},
+	}
+	for i, tt := range tests {
+		got, err := parsePressure(strings.NewReader(tt.content))
+		if (err != nil) != tt.err {
+			t.Errorf("%d: got error %v, want error %v", i, err, tt.err)
+			continue
+		}
+		if !tt.err && got != tt.want {
+			t.Errorf("%d: got %+v, want %+v", i, got, tt.want)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pressure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"cpu", "memory", "io"} {
+		b, err := ioutil.ReadFile(filepath.Join("testdata/pressure", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(root string) { pressureRoot = root }(pressureRoot)
+	pressureRoot = dir
+
+	c := newCollector()
+	s, err := c.collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Available || s.Memory.Full.Avg10 != 6.1 || s.IO.Some.Total != 91827364 || s.Memory.Some.Current != 0 {
+		t.Errorf("unexpected first sample: %+v", s)
+	}
+
+	// Memory was stalled for 100ms since the last sample which is at least that long ago
+	c.lastTime = time.Now().Add(-200 * time.Millisecond)
+	content := "some avg10=12.40 avg60=8.02 avg300=2.55 total=2934711\nfull avg10=6.10 avg60=3.97 avg300=1.20 total=1203377\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "memory"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = c.collect(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Memory.Some.Current <= 0 || s.Memory.Some.Current > 50 || s.Memory.Full.Current != 0 || s.CPU.Some.Current != 0 {
+		t.Errorf("unexpected current stalls: %+v", s)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "io")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.collect(); err == nil {
+		t.Error("expected error without I/O pressure")
+	}
+}
+
+func TestStallPercent(t *testing.T) {
+	tests := []struct {
+		last, cur uint64
+		elapsed   float64
+		want      float64
+	}{
+		{100, 600, 1000, 50},
+		{100, 100, 1000, 0},
+		{0, 5000, 1000, 100}, // Capped
+		{600, 100, 1000, 0},  // Reset
+		{100, 600, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := stallPercent(tt.last, tt.cur, tt.elapsed); got != tt.want {
+			t.Errorf("stallPercent(%d, %d, %.0f) = %.2f, want %.2f", tt.last, tt.cur, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestGetStatsUnavailable(t *testing.T) {
+	defer func(root string) { pressureRoot = root }(pressureRoot)
+	pressureRoot = "testdata/missing"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	statsChan := NewWatcher().GetStats(ctx, time.Millisecond)
+	if s := <-statsChan; s.Available || s.Reason == "" {
+		t.Errorf("got %+v, want unavailable with reason", s)
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	for s := range statsChan {
+		t.Errorf("got %+v after PSI is unavailable", s)
+	}
+}
@@ -0,0 +1,15 @@
+// +build !linux
+
+package psi
+
+import "fmt"
+
+type collector struct{}
+
+func newCollector() *collector {
+	return &collector{}
+}
+
+func (c *collector) collect() (*Stats, error) {
+	return nil, fmt.Errorf("psi: Pressure Stall Information is only available on Linux")
+}
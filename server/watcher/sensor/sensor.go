@@ -0,0 +1,183 @@
+package sensor
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sensor types
+const (
+	Fan         = "fan"
+	Voltage     = "voltage"
+	Temperature = "temperature"
+	Power       = "power"
+)
+
+type (
+	Watcher interface {
+		GetStats(ctx context.Context, interval time.Duration) <-chan *Stats
+	}
+
+	Stats struct {
+		Sensors     []Sensor `json:"sensors"`
+		CPUTemp     float64  `json:"cpuTemp"`     // Highest CPU temperature (°C)
+		StoppedFans []string `json:"stoppedFans"` // IDs of fans stopped while CPU is heating up
+	}
+
+	// Sensor holds the value of a hardware monitoring sensor, e.g.: a fan speed or a CPU temperature.
+	Sensor struct {
+		ID    string  `json:"id"` // <chip>/<sensor>, e.g.: nct6775/fan2, coretemp/temp1
+		Chip  string  `json:"chip"`
+		Type  string  `json:"type"`
+		Label string  `json:"label"`
+		Value float64 `json:"value"`
+		Unit  string  `json:"unit"` // RPM, V, °C or W
+	}
+
+	watcher struct {
+		fans      []string
+		tempDelta float64
+	}
+)
+
+// DefaultFanTempDelta is the CPU temperature rise (°C) used when the fan alert delta is not configured.
+const DefaultFanTempDelta = 5
+
+// Fans are only alerted after the CPU temperature stayed risen for this many samples
+const fanSamples = 2
+
+// Labels of fans which are checked when no fans are configured, all fans are checked if none of them is labeled so
+// (e.g.: nct6775 and it87 drivers don't label fans)
+var coolingFanLabels = []string{"cpu", "chassis", "case", "sys"}
+
+// NewWatcher returns a sensor watcher, stopped fans are checked for the fans matched any of the glob patterns
+// (CPU and chassis fans by label, or all fans if empty) when the CPU temperature rose by at least tempDelta (°C).
+func NewWatcher(fans []string, tempDelta uint) Watcher {
+	if tempDelta == 0 {
+		tempDelta = DefaultFanTempDelta
+	}
+	return &watcher{fans: fans, tempDelta: float64(tempDelta)}
+}
+
+func (w *watcher) GetStats(ctx context.Context, interval time.Duration) <-chan *Stats {
+	ticker := time.NewTicker(interval)
+	statsChan := make(chan *Stats, 10)
+	go func() {
+		logrus.Infof("watcher: SENSOR watcher started")
+		c := newCollector(w.fans, w.tempDelta)
+		for {
+			select {
+			case <-ticker.C:
+				stats, err := c.collect()
+				if err != nil {
+					logrus.Error(err)
+					continue
+				}
+				statsChan <- stats
+			case <-ctx.Done():
+				close(statsChan)
+				ticker.Stop()
+				logrus.Infof("watcher: SENSOR watcher stopped")
+				return
+			}
+		}
+	}()
+	return statsChan
+}
+
+// Match checks if the sensor ID or label matches the glob pattern.
+func (s *Sensor) Match(pattern string) bool {
+	if ok, _ := path.Match(pattern, s.ID); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, s.Label)
+	return ok
+}
+
+// fanDetector detects fans which stopped while CPU is heating up, i.e.: CPU temperature stayed at least
+// tempDelta higher than when the fan stopped for fanSamples samples in a row. Only fans which have been
+// spinning are checked since unused fan headers always report 0 RPM.
+type fanDetector struct {
+	fans      []string // Glob patterns of checked fans, CPU and chassis fans (or all fans) if empty
+	tempDelta float64
+
+	spinning map[string]bool
+	stopTemp map[string]float64 // CPU temperature when the fan stopped
+	risen    map[string]int     // Consecutive samples the CPU temperature has risen since the fan stopped
+}
+
+func newFanDetector(fans []string, tempDelta float64) *fanDetector {
+	return &fanDetector{
+		fans:      fans,
+		tempDelta: tempDelta,
+		spinning:  make(map[string]bool),
+		stopTemp:  make(map[string]float64),
+		risen:     make(map[string]int),
+	}
+}
+
+// checked returns true if the fan is checked for stopping, allFans is used when no fans are configured.
+func (d *fanDetector) checked(s *Sensor, allFans bool) bool {
+	if len(d.fans) == 0 {
+		return allFans || isCoolingFan(s)
+	}
+	for _, pattern := range d.fans {
+		if s.Match(pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCoolingFan(s *Sensor) bool {
+	label := strings.ToLower(s.Label)
+	for _, l := range coolingFanLabels {
+		if strings.Contains(label, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *fanDetector) detect(stats *Stats) {
+	stats.StoppedFans = make([]string, 0)
+	allFans := true
+	for i := range stats.Sensors {
+		if s := &stats.Sensors[i]; s.Type == Fan && isCoolingFan(s) {
+			allFans = false
+			break
+		}
+	}
+	for i := range stats.Sensors {
+		s := &stats.Sensors[i]
+		if s.Type != Fan || !d.checked(s, allFans) {
+			continue
+		}
+		if s.Value > 0 {
+			d.spinning[s.ID] = true
+			delete(d.stopTemp, s.ID)
+			delete(d.risen, s.ID)
+			continue
+		}
+		if !d.spinning[s.ID] {
+			continue
+		}
+		t, ok := d.stopTemp[s.ID]
+		if !ok {
+			d.stopTemp[s.ID] = stats.CPUTemp
+			continue
+		}
+		if stats.CPUTemp < t+d.tempDelta {
+			d.risen[s.ID] = 0
+			continue
+		}
+		d.risen[s.ID]++
+		if d.risen[s.ID] >= fanSamples {
+			stats.StoppedFans = append(stats.StoppedFans, s.ID)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// +build linux
+
+package sensor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lnquy/nights-watch/server/util"
+)
+
+var (
+	hwmonRoot = "/sys/class/hwmon"
+
+	inputRegex = regexp.MustCompile(`^(fan|in|temp|power)([0-9]+)_(input|average)$`)
+	// Chips which report CPU temperatures
+	cpuChips = map[string]bool{"coretemp": true, "k10temp": true, "zenpower": true, "cpu_thermal": true}
+)
+
+type collector struct {
+	fans *fanDetector
+}
+
+func newCollector(fans []string, tempDelta float64) *collector {
+	return &collector{fans: newFanDetector(fans, tempDelta)}
+}
+
+func (c *collector) collect() (*Stats, error) {
+	dirs, err := filepath.Glob(filepath.Join(hwmonRoot, "hwmon*"))
+	if err != nil {
+		return nil, fmt.Errorf("sensor: failed to list hwmon devices: %s", err)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return hwmonIndex(dirs[i]) < hwmonIndex(dirs[j])
+	})
+	stats := &Stats{Sensors: make([]Sensor, 0)}
+	chips := make(map[string]int) // Occurrences of chip names
+	for _, dir := range dirs {
+		name := util.ReadString(filepath.Join(dir, "name"))
+		if name == "" {
+			continue
+		}
+		chip := name
+		if n := chips[name]; n > 0 { // E.g.: multiple NVMe drives
+			chip = fmt.Sprintf("%s-%d", name, n)
+		}
+		chips[name]++
+
+		for _, s := range readSensors(dir, chip) {
+			if s.Type == Temperature && cpuChips[name] && s.Value > stats.CPUTemp {
+				stats.CPUTemp = s.Value
+			}
+			stats.Sensors = append(stats.Sensors, s)
+		}
+	}
+	c.fans.detect(stats)
+	return stats, nil
+}
+
+// readSensors reads all sensors of a hwmon chip, values are converted from millidegree Celsius,
+// millivolt and microwatt.
+func readSensors(dir, chip string) []Sensor {
+	files, _ := ioutil.ReadDir(dir)
+	sensors := make([]Sensor, 0)
+	seen := make(map[string]bool)
+	for _, f := range files {
+		m := inputRegex.FindStringSubmatch(f.Name())
+		if m == nil || seen[m[1]+m[2]] { // Power can have both input and average
+			continue
+		}
+		raw, err := strconv.ParseFloat(util.ReadString(filepath.Join(dir, f.Name())), 64)
+		if err != nil {
+			continue // Sensor is not available, e.g.: fan disconnected
+		}
+		seen[m[1]+m[2]] = true
+		s := Sensor{
+			ID:    chip + "/" + m[1] + m[2],
+			Chip:  chip,
+			Label: util.ReadString(filepath.Join(dir, m[1]+m[2]+"_label")),
+		}
+		switch m[1] {
+		case "fan":
+			s.Type, s.Value, s.Unit = Fan, raw, "RPM"
+		case "in":
+			s.Type, s.Value, s.Unit = Voltage, raw/1000, "V"
+		case "temp":
+			s.Type, s.Value, s.Unit = Temperature, raw/1000, "°C"
+		case "power":
+			s.Type, s.Value, s.Unit = Power, raw/1000000, "W"
+		}
+		if s.Label == "" {
+			s.Label = m[1] + m[2]
+		}
+		sensors = append(sensors, s)
+	}
+	return sensors
+}
+
+func hwmonIndex(dir string) int {
+	i, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "hwmon"))
+	return i
+}
@@ -0,0 +1,87 @@
+// +build linux
+
+package sensor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Fixtures of hwmon in testdata/hwmon: coretemp (hwmon0) and a nct6775 Super I/O chip (hwmon2)
+// which doesn't label its fans, fan3 header is unused.
+const testHwmonRoot = "testdata/hwmon"
+
+func TestReadSensors(t *testing.T) {
+	want := []Sensor{
+		{ID: "nct6775/fan1", Chip: "nct6775", Type: Fan, Label: "fan1", Value: 1150, Unit: "RPM"},
+		{ID: "nct6775/fan2", Chip: "nct6775", Type: Fan, Label: "fan2", Value: 820, Unit: "RPM"},
+		{ID: "nct6775/fan3", Chip: "nct6775", Type: Fan, Label: "fan3", Value: 0, Unit: "RPM"},
+		{ID: "nct6775/in0", Chip: "nct6775", Type: Voltage, Label: "in0", Value: 1.024, Unit: "V"},
+		{ID: "nct6775/temp1", Chip: "nct6775", Type: Temperature, Label: "SYSTIN", Value: 35, Unit: "°C"},
+	}
+	if got := readSensors(filepath.Join(testHwmonRoot, "hwmon2"), "nct6775"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+// copyHwmon copies the hwmon fixtures to a temporary directory which can be changed between samples.
+func copyHwmon(t *testing.T) string {
+	root, err := ioutil.TempDir("", "hwmon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = filepath.Walk(testHwmonRoot, func(fp string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(testHwmonRoot, fp)
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(root, rel), 0755)
+		}
+		b, err := ioutil.ReadFile(fp)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(root, rel), b, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestCollectUnlabeledFans(t *testing.T) {
+	defer func(root string) { hwmonRoot = root }(hwmonRoot)
+	hwmonRoot = copyHwmon(t)
+	defer os.RemoveAll(hwmonRoot)
+
+	write := func(name, value string) {
+		if err := ioutil.WriteFile(filepath.Join(hwmonRoot, name), []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := newCollector(nil, DefaultFanTempDelta)
+	steps := []struct {
+		fan1, cpuTemp string
+		want          []string
+	}{
+		{"1150", "40000", []string{}},
+		{"0", "40000", []string{}}, // Stopped at 40°C
+		{"0", "46000", []string{}},
+		{"0", "47000", []string{"nct6775/fan1"}}, // Unused fan3 is never reported
+	}
+	for i, step := range steps {
+		write("hwmon2/fan1_input", step.fan1)
+		write("hwmon0/temp1_input", step.cpuTemp)
+		stats, err := c.collect()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(stats.StoppedFans, step.want) {
+			t.Errorf("%d: got stopped fans %v, want %v", i, stats.StoppedFans, step.want)
+		}
+	}
+}
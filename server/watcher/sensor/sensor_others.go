@@ -0,0 +1,16 @@
+// +build !linux
+
+package sensor
+
+import "fmt"
+
+// TODO: Windows/Darwin
+type collector struct{}
+
+func newCollector(fans []string, tempDelta float64) *collector {
+	return &collector{}
+}
+
+func (c *collector) collect() (*Stats, error) {
+	return nil, fmt.Errorf("sensor: hwmon sensors are only available on Linux")
+}
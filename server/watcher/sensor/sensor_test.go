@@ -0,0 +1,136 @@
+package sensor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSensorMatch(t *testing.T) {
+	s := Sensor{ID: "nct6775/fan2", Label: "CPU Fan"}
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"nct6775/fan2", true},
+		{"nct6775/fan*", true},
+		{"CPU*", true},
+		{"*/fan1", false},
+		{"Chassis*", false},
+	}
+	for i, tt := range tests {
+		if got := s.Match(tt.pattern); got != tt.want {
+			t.Errorf("%d: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestFanDetector(t *testing.T) {
+	fans := func(cpu, chassis, pump float64) []Sensor {
+		return []Sensor{
+			{ID: "nct6775/fan1", Type: Fan, Label: "CPU Fan", Value: cpu},
+			{ID: "nct6775/fan2", Type: Fan, Label: "Chassis Fan 1", Value: chassis},
+			{ID: "nct6775/fan3", Type: Fan, Label: "AIO Pump", Value: pump},
+			{ID: "coretemp/temp1", Type: Temperature, Label: "Package id 0", Value: 99},
+		}
+	}
+	tests := []struct {
+		patterns []string
+		samples  []Stats
+		want     [][]string
+	}{
+		{ // CPU and chassis fans by default, pump is not checked
+			nil,
+			[]Stats{
+				{Sensors: fans(1200, 800, 2000), CPUTemp: 40},
+				{Sensors: fans(0, 0, 0), CPUTemp: 40},
+				{Sensors: fans(0, 0, 0), CPUTemp: 45},
+				{Sensors: fans(0, 0, 0), CPUTemp: 46},
+			},
+			[][]string{{}, {}, {}, {"nct6775/fan1", "nct6775/fan2"}},
+		},
+		{ // Rise below delta and a single risen sample are ignored
+			nil,
+			[]Stats{
+				{Sensors: fans(1200, 0, 0), CPUTemp: 40},
+				{Sensors: fans(0, 0, 0), CPUTemp: 40},
+				{Sensors: fans(0, 0, 0), CPUTemp: 44},
+				{Sensors: fans(0, 0, 0), CPUTemp: 50},
+				{Sensors: fans(0, 0, 0), CPUTemp: 41},
+				{Sensors: fans(0, 0, 0), CPUTemp: 50},
+				{Sensors: fans(0, 0, 0), CPUTemp: 50},
+			},
+			[][]string{{}, {}, {}, {}, {}, {}, {"nct6775/fan1"}},
+		},
+		{ // Fan spinning again resets the stop temperature
+			nil,
+			[]Stats{
+				{Sensors: fans(1200, 0, 0), CPUTemp: 40},
+				{Sensors: fans(0, 0, 0), CPUTemp: 40},
+				{Sensors: fans(0, 0, 0), CPUTemp: 50},
+				{Sensors: fans(1200, 0, 0), CPUTemp: 50},
+				{Sensors: fans(0, 0, 0), CPUTemp: 50},
+				{Sensors: fans(0, 0, 0), CPUTemp: 52},
+			},
+			[][]string{{}, {}, {}, {}, {}, {}},
+		},
+		{ // Configured fans
+			[]string{"*/fan3"},
+			[]Stats{
+				{Sensors: fans(1200, 800, 2000), CPUTemp: 40},
+				{Sensors: fans(0, 0, 0), CPUTemp: 40},
+				{Sensors: fans(0, 0, 0), CPUTemp: 45},
+				{Sensors: fans(0, 0, 0), CPUTemp: 45},
+			},
+			[][]string{{}, {}, {}, {"nct6775/fan3"}},
+		},
+		{ // Unused fan headers always report 0 RPM
+			nil,
+			[]Stats{
+				{Sensors: fans(0, 0, 0), CPUTemp: 40},
+				{Sensors: fans(0, 0, 0), CPUTemp: 60},
+				{Sensors: fans(0, 0, 0), CPUTemp: 80},
+			},
+			[][]string{{}, {}, {}},
+		},
+	}
+	for i, tt := range tests {
+		d := newFanDetector(tt.patterns, DefaultFanTempDelta)
+		for j := range tt.samples {
+			d.detect(&tt.samples[j])
+			if got := tt.samples[j].StoppedFans; !reflect.DeepEqual(got, tt.want[j]) {
+				t.Errorf("%d: sample %d: got stopped fans %v, want %v", i, j, got, tt.want[j])
+			}
+		}
+	}
+}
+
+func TestFanDetectorUnlabeled(t *testing.T) {
+	fans := func(fan1, fan2 float64, label2 string) []Sensor {
+		return []Sensor{
+			{ID: "it87/fan1", Type: Fan, Label: "fan1", Value: fan1},
+			{ID: "it87/fan2", Type: Fan, Label: label2, Value: fan2},
+		}
+	}
+	tests := []struct {
+		label2 string
+		want   []string
+	}{
+		{"fan2", []string{"it87/fan1", "it87/fan2"}}, // No fan is labeled, all fans are checked
+		{"CPU_FAN", []string{"it87/fan2"}},           // Only labeled fans are checked
+	}
+	for i, tt := range tests {
+		d := newFanDetector(nil, DefaultFanTempDelta)
+		samples := []Stats{
+			{Sensors: fans(1200, 800, tt.label2), CPUTemp: 40},
+			{Sensors: fans(0, 0, tt.label2), CPUTemp: 40},
+			{Sensors: fans(0, 0, tt.label2), CPUTemp: 50},
+			{Sensors: fans(0, 0, tt.label2), CPUTemp: 50},
+		}
+		for j := range samples {
+			d.detect(&samples[j])
+		}
+		if got := samples[3].StoppedFans; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%d: got stopped fans %v, want %v", i, got, tt.want)
+		}
+	}
+}